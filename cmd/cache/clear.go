@@ -0,0 +1,28 @@
+package cache
+
+import (
+	"github.com/marcbran/jpoet/internal/terminal"
+	"github.com/spf13/cobra"
+)
+
+var clearCmd = &cobra.Command{
+	Use:   "clear [flags]",
+	Short: "Removes all cached plugin results",
+	Long:  ``,
+
+	DisableAutoGenTag: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+		cmd.SilenceErrors = true
+		cache, err := diskCache(cmd)
+		if err != nil {
+			return err
+		}
+		err = cache.Clear()
+		if err != nil {
+			return err
+		}
+		terminal.Success("Cleared the cache of plugin results")
+		return nil
+	},
+}
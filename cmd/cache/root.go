@@ -0,0 +1,34 @@
+package cache
+
+import (
+	"github.com/marcbran/jpoet/pkg/jpoet"
+	"github.com/spf13/cobra"
+)
+
+var Cmd = &cobra.Command{
+	Use:   "cache",
+	Short: "Subcommands for inspecting and clearing the cache of plugin results",
+	Long:  ``,
+
+	DisableAutoGenTag: true,
+}
+
+func init() {
+	Cmd.PersistentFlags().String("cache-dir", "", "Directory of the plugin result cache, defaults to the jpoet directory in the user cache directory")
+	Cmd.AddCommand(statsCmd)
+	Cmd.AddCommand(clearCmd)
+}
+
+func diskCache(cmd *cobra.Command) (*jpoet.DiskCache, error) {
+	dir, err := cmd.Flags().GetString("cache-dir")
+	if err != nil {
+		return nil, err
+	}
+	if dir == "" {
+		dir, err = jpoet.DefaultCacheDir()
+		if err != nil {
+			return nil, err
+		}
+	}
+	return jpoet.NewDiskCache(dir), nil
+}
@@ -0,0 +1,32 @@
+package cache
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+)
+
+var statsCmd = &cobra.Command{
+	Use:   "stats [flags]",
+	Short: "Shows how many plugin results are cached and how much space they take",
+	Long:  ``,
+
+	DisableAutoGenTag: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+		cmd.SilenceErrors = true
+		cache, err := diskCache(cmd)
+		if err != nil {
+			return err
+		}
+		stats, err := cache.Stats()
+		if err != nil {
+			return err
+		}
+		out := cmd.OutOrStdout()
+		_, _ = fmt.Fprintf(out, "Entries: %d\n", stats.Entries)
+		_, _ = fmt.Fprintf(out, "Expired: %d\n", stats.Expired)
+		_, _ = fmt.Fprintf(out, "Size:    %d bytes\n", stats.Size)
+		return nil
+	},
+}
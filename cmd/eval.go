@@ -2,6 +2,7 @@ package cmd
 
 import (
 	"bufio"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -36,6 +37,14 @@ var evalCmd = &cobra.Command{
 		if err != nil {
 			return err
 		}
+		trace, err := cmd.Flags().GetString("trace")
+		if err != nil {
+			return err
+		}
+		withMetrics, err := cmd.Flags().GetBool("metrics")
+		if err != nil {
+			return err
+		}
 
 		arg := ""
 		if len(args) > 0 {
@@ -60,7 +69,7 @@ var evalCmd = &cobra.Command{
 			inputOpt = jpoet.FileInput(filepath.Join(directory, arg))
 		}
 
-		plugins, err := jpoet.NewPluginsDir(filepath.Join(directory, ".jpoet", "plugins"))
+		plugins, done, err := loadPlugins(cmd, directory)
 		if err != nil {
 			return err
 		}
@@ -70,15 +79,37 @@ var evalCmd = &cobra.Command{
 			outputOpt = jpoet.DirectoryOutput(outputDirectory)
 		}
 
-		err = jpoet.Eval(
-			jpoet.WithPluginSet(plugins...),
-			inputOpt,
-			jpoet.Serialize(!str),
-			outputOpt,
-		)
+		opts := []jpoet.Option{inputOpt, jpoet.Serialize(!str), outputOpt}
+		var tracer *jpoet.Tracer
+		if trace != "" {
+			tracer = jpoet.NewTracer()
+			for i, p := range plugins {
+				plugins[i] = p.WithPluginMiddleware(tracer.Middleware())
+			}
+			opts = append(opts, jpoet.WithTracer(tracer))
+		}
+		var metrics *jpoet.Metrics
+		if withMetrics {
+			metrics = jpoet.NewMetrics()
+			for i, p := range plugins {
+				plugins[i] = p.WithPluginMiddleware(jpoet.MetricsMiddleware(metrics))
+			}
+		}
+
+		err = jpoet.Eval(append(opts, jpoet.WithPluginSet(plugins...))...)
+		derr := done()
+		if tracer != nil {
+			derr = errors.Join(derr, tracer.Save(trace))
+		}
+		if metrics != nil {
+			derr = errors.Join(derr, writeMetricsSummary(cmd.ErrOrStderr(), metrics))
+		}
 		if err != nil {
 			return err
 		}
+		if derr != nil {
+			return derr
+		}
 		return nil
 	},
 }
@@ -88,4 +119,7 @@ func init() {
 	evalCmd.Flags().BoolP("code", "c", false, "Treat provided input as code")
 	evalCmd.Flags().BoolP("string", "s", false, "Output raw string instead of Json serialization but fails if evaluated output is not a string")
 	evalCmd.Flags().StringP("output-directory", "o", "", "Write output files to this directory instead of stdout")
+	evalCmd.Flags().Bool("metrics", false, "Print the number of invocations, errors and latencies of every plugin function to stderr at the end")
+	evalCmd.Flags().String("trace", "", "Write spans for VM setup, imports, plugin invocations and output to this file as Chrome trace events")
+	addPluginFlags(evalCmd)
 }
@@ -0,0 +1,357 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/marcbran/jpoet/internal/pkg"
+	"github.com/marcbran/jpoet/pkg/jpoet"
+	"github.com/spf13/cobra"
+)
+
+func addPluginFlags(cmd *cobra.Command) {
+	cmd.Flags().String("reattach", "", "Connect to plugins running in debug mode instead of starting them, defaults to $"+jpoet.ReattachEnv)
+	cmd.Flags().StringArray("plugin-setting", nil, "Setting delivered to a plugin at startup, in the form plugin.key=value, where value may be JSON")
+	cmd.Flags().StringArray("plugin-env", nil, "Environment variable of a plugin process, in the form plugin.KEY=value")
+	cmd.Flags().StringArray("plugin-limit", nil, "Resource limit of a plugin process on Linux, in the form plugin.limit=value, where limit is memory, cpuTime, openFiles, processes or cgroup")
+	cmd.Flags().StringArray("plugin-timeout", nil, "Timeout of the invocations of a plugin function, in the form plugin.function=duration, where function default applies to all functions")
+	cmd.Flags().StringArray("plugin-retry", nil, "Retry of plugin invocations that fail with retryable errors, in the form plugin.setting=value, where setting is attempts, backoff or maxBackoff")
+	cmd.Flags().Bool("cache", false, "Cache the results of plugin functions that their plugins declare cacheable on disk, so that later runs can reuse them")
+	cmd.Flags().Duration("cache-ttl", time.Hour, "How long cached results of pure plugin functions are reused, and the most for functions that suggest a time of their own")
+	cmd.Flags().String("cache-dir", "", "Directory of the plugin result cache, defaults to the jpoet directory in the user cache directory")
+	cmd.Flags().String("record", "", "Record all plugin invocations and their results into this cassette file")
+	cmd.Flags().String("replay", "", "Serve plugin invocations from this cassette file instead of invoking the plugins, failing invocations that have not been recorded")
+	cmd.MarkFlagsMutuallyExclusive("record", "replay")
+	cmd.Flags().String("policy", "", "Jsonnet or JSON file with allow and deny lists of plugin.function patterns, enforced on top of the policy in pkg.libsonnet")
+	cmd.Flags().String("audit-log", "", "Append a line of JSON for every plugin invocation to this file, with secret-looking arguments and results masked")
+	cmd.Flags().Bool("audit-hash", false, "Write hashes of the arguments and results of plugin invocations to the audit log instead of their values, keyed per run so that they can only be compared within a run")
+	cmd.Flags().StringArray("audit-redact", nil, "Additional redaction rule of the audit log, in the form kind=pattern, where kind is function, key or value and pattern is a regular expression")
+}
+
+func loadPlugins(cmd *cobra.Command, directory string) ([]*jpoet.Plugin, func() error, error) {
+	reattach, err := cmd.Flags().GetString("reattach")
+	if err != nil {
+		return nil, nil, err
+	}
+	if reattach == "" {
+		reattach = os.Getenv(jpoet.ReattachEnv)
+	}
+
+	cfg, err := pkg.LoadPkgConfig(directory)
+	if err != nil {
+		return nil, nil, err
+	}
+	configs, err := pluginConfigs(cmd, directory, cfg)
+	if err != nil {
+		return nil, nil, err
+	}
+	installed, missing, err := pkg.PluginDirs(directory, cfg)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	opts := []jpoet.PluginsOption{
+		jpoet.PluginsInstalled(installed),
+		jpoet.PluginsMissing(missing),
+		jpoet.PluginsDir(filepath.Join(directory, ".jpoet", "plugins")),
+		jpoet.PluginsConfig(configs),
+	}
+	if reattach != "" {
+		configs, err := jpoet.ParseReattachConfigs(reattach)
+		if err != nil {
+			return nil, nil, err
+		}
+		opts = append(opts, jpoet.PluginsReattach(configs))
+	}
+	plugins, err := jpoet.LoadPlugins(opts...)
+	if err != nil {
+		return nil, nil, err
+	}
+	plugins, err = withCache(cmd, plugins)
+	if err != nil {
+		return nil, nil, err
+	}
+	plugins, saveCassette, err := withCassette(cmd, plugins)
+	if err != nil {
+		return nil, nil, err
+	}
+	plugins, err = withPolicy(cmd, cfg, plugins)
+	if err != nil {
+		return nil, nil, err
+	}
+	plugins, closeAuditLog, err := withAuditLog(cmd, plugins)
+	if err != nil {
+		return nil, nil, err
+	}
+	return plugins, func() error {
+		return errors.Join(saveCassette(), closeAuditLog())
+	}, nil
+}
+
+func withCache(cmd *cobra.Command, plugins []*jpoet.Plugin) ([]*jpoet.Plugin, error) {
+	enabled, err := cmd.Flags().GetBool("cache")
+	if err != nil {
+		return nil, err
+	}
+	if !enabled {
+		return plugins, nil
+	}
+	ttl, err := cmd.Flags().GetDuration("cache-ttl")
+	if err != nil {
+		return nil, err
+	}
+	dir, err := cmd.Flags().GetString("cache-dir")
+	if err != nil {
+		return nil, err
+	}
+	cache, err := diskCache(dir)
+	if err != nil {
+		return nil, err
+	}
+	flights := jpoet.NewSingleflight()
+	for i, p := range plugins {
+		plugins[i] = p.WithPluginMiddleware(cache.HintedWriter(ttl), flights.Middleware(), cache.Reader())
+	}
+	return plugins, nil
+}
+
+func withCassette(cmd *cobra.Command, plugins []*jpoet.Plugin) ([]*jpoet.Plugin, func() error, error) {
+	record, err := cmd.Flags().GetString("record")
+	if err != nil {
+		return nil, nil, err
+	}
+	replay, err := cmd.Flags().GetString("replay")
+	if err != nil {
+		return nil, nil, err
+	}
+	switch {
+	case replay != "":
+		cassette, err := jpoet.LoadCassette(replay)
+		if err != nil {
+			return nil, nil, err
+		}
+		for i, p := range plugins {
+			plugins[i] = p.WithPluginMiddleware(cassette.Replayer())
+		}
+		return plugins, func() error { return nil }, nil
+	case record != "":
+		cassette := jpoet.NewCassette()
+		for i, p := range plugins {
+			plugins[i] = p.WithPluginMiddleware(cassette.Recorder())
+		}
+		return plugins, func() error { return cassette.Save(record) }, nil
+	default:
+		return plugins, func() error { return nil }, nil
+	}
+}
+
+func withPolicy(cmd *cobra.Command, cfg pkg.Config, plugins []*jpoet.Plugin) ([]*jpoet.Plugin, error) {
+	policies := make([]jpoet.PluginMiddleware, 0, 2)
+	policy, err := pkg.Policy(cfg)
+	if err != nil {
+		return nil, err
+	}
+	if !policy.IsZero() {
+		policies = append(policies, policy.Middleware())
+	}
+	file, err := cmd.Flags().GetString("policy")
+	if err != nil {
+		return nil, err
+	}
+	if file != "" {
+		policy, err := jpoet.LoadPluginPolicy(file)
+		if err != nil {
+			return nil, err
+		}
+		policies = append(policies, policy.Middleware())
+	}
+	if len(policies) == 0 {
+		return plugins, nil
+	}
+	for i, p := range plugins {
+		plugins[i] = p.WithPluginMiddleware(policies...)
+	}
+	return plugins, nil
+}
+
+func withAuditLog(cmd *cobra.Command, plugins []*jpoet.Plugin) ([]*jpoet.Plugin, func() error, error) {
+	path, err := cmd.Flags().GetString("audit-log")
+	if err != nil {
+		return nil, nil, err
+	}
+	if path == "" {
+		return plugins, func() error { return nil }, nil
+	}
+	hash, err := cmd.Flags().GetBool("audit-hash")
+	if err != nil {
+		return nil, nil, err
+	}
+	redacts, err := cmd.Flags().GetStringArray("audit-redact")
+	if err != nil {
+		return nil, nil, err
+	}
+	var rules []jpoet.RedactRule
+	for _, redact := range redacts {
+		rule, err := jpoet.ParseRedactRule(redact)
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid --audit-redact: %w", err)
+		}
+		rules = append(rules, rule)
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o600)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to open audit log: %w", err)
+	}
+	log := jpoet.NewAuditLog(f, jpoet.AuditHash(hash), jpoet.AuditRedact(rules...))
+	for i, p := range plugins {
+		plugins[i] = p.WithPluginMiddleware(log.Middleware())
+	}
+	return plugins, f.Close, nil
+}
+
+func writeMetricsSummary(w io.Writer, metrics *jpoet.Metrics) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(tw, "PLUGIN\tFUNCTION\tCALLS\tERRORS\tMEAN\tP50\tP99\tMAX")
+	for _, f := range metrics.Snapshot() {
+		duration := func(seconds float64) time.Duration {
+			return min(time.Duration(seconds*float64(time.Second)), f.Max).Round(time.Microsecond)
+		}
+		_, _ = fmt.Fprintf(tw, "%s\t%s\t%d\t%d\t%s\t%s\t%s\t%s\n",
+			f.Plugin, f.Function, f.Calls, f.Errors,
+			duration(f.Latency.Sum/float64(f.Latency.Count)), duration(f.Latency.Quantile(0.5)), duration(f.Latency.Quantile(0.99)), duration(f.Max.Seconds()))
+	}
+	return tw.Flush()
+}
+
+func diskCache(dir string) (*jpoet.DiskCache, error) {
+	if dir == "" {
+		var err error
+		dir, err = jpoet.DefaultCacheDir()
+		if err != nil {
+			return nil, err
+		}
+	}
+	return jpoet.NewDiskCache(dir), nil
+}
+
+func pluginConfigs(cmd *cobra.Command, directory string, cfg pkg.Config) (map[string]jpoet.PluginConfig, error) {
+	configs := pkg.PluginConfigs(directory, cfg)
+	settings, err := cmd.Flags().GetStringArray("plugin-setting")
+	if err != nil {
+		return nil, err
+	}
+	for _, setting := range settings {
+		name, key, value, err := parsePluginFlag(setting)
+		if err != nil {
+			return nil, fmt.Errorf("invalid --plugin-setting: %w", err)
+		}
+		config := configs[name]
+		config.Settings = maps.Clone(config.Settings)
+		if config.Settings == nil {
+			config.Settings = make(map[string]any)
+		}
+		var v any
+		err = json.Unmarshal([]byte(value), &v)
+		if err != nil {
+			v = value
+		}
+		config.Settings[key] = v
+		configs[name] = config
+	}
+	envs, err := cmd.Flags().GetStringArray("plugin-env")
+	if err != nil {
+		return nil, err
+	}
+	for _, env := range envs {
+		name, key, value, err := parsePluginFlag(env)
+		if err != nil {
+			return nil, fmt.Errorf("invalid --plugin-env: %w", err)
+		}
+		config := configs[name]
+		config.Env = maps.Clone(config.Env)
+		if config.Env == nil {
+			config.Env = make(map[string]string)
+		}
+		config.Env[key] = value
+		configs[name] = config
+	}
+	limits, err := cmd.Flags().GetStringArray("plugin-limit")
+	if err != nil {
+		return nil, err
+	}
+	for _, limit := range limits {
+		name, key, value, err := parsePluginFlag(limit)
+		if err != nil {
+			return nil, fmt.Errorf("invalid --plugin-limit: %w", err)
+		}
+		config := configs[name]
+		err = config.Limits.Set(key, value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid --plugin-limit: %w", err)
+		}
+		configs[name] = config
+	}
+	timeouts, err := cmd.Flags().GetStringArray("plugin-timeout")
+	if err != nil {
+		return nil, err
+	}
+	for _, timeout := range timeouts {
+		name, funcName, value, err := parsePluginFlag(timeout)
+		if err != nil {
+			return nil, fmt.Errorf("invalid --plugin-timeout: %w", err)
+		}
+		config := configs[name]
+		err = config.Timeouts.Set(funcName, value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid --plugin-timeout: %w", err)
+		}
+		configs[name] = config
+	}
+	retries, err := cmd.Flags().GetStringArray("plugin-retry")
+	if err != nil {
+		return nil, err
+	}
+	for _, retry := range retries {
+		name, key, value, err := parsePluginFlag(retry)
+		if err != nil {
+			return nil, fmt.Errorf("invalid --plugin-retry: %w", err)
+		}
+		config := configs[name]
+		err = config.Retry.Set(key, value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid --plugin-retry: %w", err)
+		}
+		configs[name] = config
+	}
+	return configs, nil
+}
+
+func parsePluginFlag(s string) (string, string, string, error) {
+	name, rest, ok := strings.Cut(s, ".")
+	if !ok {
+		return "", "", "", fmt.Errorf("expected plugin.key=value, got %q", s)
+	}
+	key, value, ok := strings.Cut(rest, "=")
+	if !ok {
+		return "", "", "", fmt.Errorf("expected plugin.key=value, got %q", s)
+	}
+	return name, key, value, nil
+}
+
+func closePlugins(plugins []*jpoet.Plugin) error {
+	for _, p := range plugins {
+		err := p.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
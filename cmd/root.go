@@ -2,6 +2,7 @@ package cmd
 
 import (
 	"fmt"
+	"github.com/marcbran/jpoet/cmd/cache"
 	"github.com/marcbran/jpoet/cmd/pkg"
 	"github.com/marcbran/jpoet/cmd/repo"
 	"github.com/marcbran/jpoet/internal/terminal"
@@ -47,6 +48,7 @@ func init() {
 	Cmd.AddCommand(testCmd)
 	Cmd.AddCommand(installCmd)
 	Cmd.AddCommand(evalCmd)
+	Cmd.AddCommand(cache.Cmd)
 	Cmd.AddCommand(pkg.Cmd)
 	Cmd.AddCommand(repo.Cmd)
 }
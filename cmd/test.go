@@ -25,10 +25,22 @@ var testCmd = &cobra.Command{
 		if err != nil {
 			return err
 		}
-		run, err := test.RunDir(dirname)
+		plugins, done, err := loadPlugins(cmd, dirname)
 		if err != nil {
 			return err
 		}
+		run, err := test.RunDir(dirname, plugins...)
+		derr := done()
+		cerr := closePlugins(plugins)
+		if err != nil {
+			return err
+		}
+		if derr != nil {
+			return derr
+		}
+		if cerr != nil {
+			return cerr
+		}
 		if j {
 			b, err := json.Marshal(run)
 			if err != nil {
@@ -66,4 +78,5 @@ var testCmd = &cobra.Command{
 
 func init() {
 	testCmd.Flags().BoolP("json", "j", false, "Outputs the test results in JSON")
+	addPluginFlags(testCmd)
 }
@@ -12,6 +12,7 @@ require (
 	github.com/marcbran/jsonnet-plugin-jsonnet v0.3.0
 	github.com/marcbran/jsonnet-plugin-markdown v0.2.1
 	github.com/spf13/cobra v1.10.2
+	golang.org/x/sys v0.43.0
 	google.golang.org/grpc v1.82.0
 	google.golang.org/protobuf v1.36.11
 )
@@ -49,7 +50,6 @@ require (
 	go.yaml.in/yaml/v3 v3.0.4 // indirect
 	golang.org/x/crypto v0.50.0 // indirect
 	golang.org/x/net v0.53.0 // indirect
-	golang.org/x/sys v0.43.0 // indirect
 	golang.org/x/text v0.36.0 // indirect
 	google.golang.org/genproto/googleapis/rpc v0.0.0-20260414002931-afd174a4e478 // indirect
 	gopkg.in/warnings.v0 v0.1.2 // indirect
@@ -23,13 +23,16 @@ func Install(ctx context.Context, pkgDir string) error {
 	if err != nil {
 		return err
 	}
-	pluginsDir := filepath.Join(pkgDir, ".jpoet", "plugins")
-	err = os.MkdirAll(pluginsDir, 0755)
+	err = checkPluginNames(cfg.Plugins)
+	if err != nil {
+		return err
+	}
+	stores, err := PluginPath()
 	if err != nil {
 		return err
 	}
 	for _, plugin := range cfg.Plugins {
-		err := plugin.install(ctx, pluginsDir)
+		err := plugin.install(ctx, stores)
 		if err != nil {
 			return err
 		}
@@ -37,14 +40,22 @@ func Install(ctx context.Context, pkgDir string) error {
 	return nil
 }
 
-func (p Plugin) install(ctx context.Context, pluginsDir string) error {
+func (p Plugin) install(ctx context.Context, stores []string) error {
 	if p.Github != nil {
-		return p.Github.Install(ctx, pluginsDir)
+		return p.Github.Install(ctx, stores)
 	}
 	return nil
 }
 
-func (p GithubPlugin) Install(ctx context.Context, pluginsDir string) error {
+func (p GithubPlugin) Install(ctx context.Context, stores []string) error {
+	_, ok, err := p.find(stores)
+	if err != nil {
+		return err
+	}
+	if ok {
+		terminal.Infof("Plugin from GitHub repository %s at version %s is already installed", p.Repo, p.Version)
+		return nil
+	}
 	terminal.Infof("Fetching plugin from GitHub repository %s at version %s", p.Repo, p.Version)
 	client := github.NewClient(nil)
 	owner := strings.Split(p.Repo, "/")[0]
@@ -72,16 +83,39 @@ func (p GithubPlugin) Install(ctx context.Context, pluginsDir string) error {
 		return fmt.Errorf("checksum verification failed: %w", err)
 	}
 
-	pluginDir := filepath.Join(pluginsDir, repo)
-	err = os.MkdirAll(pluginDir, 0755)
+	pluginDir := p.storeDir(stores[0])
+	err = os.MkdirAll(filepath.Dir(pluginDir), 0755)
 	if err != nil {
 		return fmt.Errorf("failed to create plugin directory: %w", err)
 	}
 
-	err = extractTarGz(tempFile, pluginDir)
+	tempDir, err := os.MkdirTemp(filepath.Dir(pluginDir), p.Version+".tmp-")
+	if err != nil {
+		return fmt.Errorf("failed to create plugin directory: %w", err)
+	}
+	defer func() {
+		_ = os.RemoveAll(tempDir)
+	}()
+	err = os.Chmod(tempDir, 0755)
+	if err != nil {
+		return fmt.Errorf("failed to create plugin directory: %w", err)
+	}
+	err = extractTarGz(tempFile, tempDir)
 	if err != nil {
 		return fmt.Errorf("failed to extract plugin: %w", err)
 	}
+	err = p.writeManifest(tempDir)
+	if err != nil {
+		return err
+	}
+	err = os.Rename(tempDir, pluginDir)
+	if err != nil {
+		_, installed, _ := p.find(stores[:1])
+		if installed {
+			return nil
+		}
+		return fmt.Errorf("failed to install plugin: %w", err)
+	}
 
 	return nil
 }
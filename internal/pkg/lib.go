@@ -13,11 +13,12 @@ import (
 var lib embed.FS
 
 type Config struct {
-	Source      string      `json:"source"`
-	Description string      `json:"description"`
-	Coordinates Coordinates `json:"coordinates"`
-	Usage       Usage       `json:"usage"`
-	Plugins     []Plugin    `json:"plugins"`
+	Source      string             `json:"source"`
+	Description string             `json:"description"`
+	Coordinates Coordinates        `json:"coordinates"`
+	Usage       Usage              `json:"usage"`
+	Plugins     []Plugin           `json:"plugins"`
+	Policy      jpoet.PluginPolicy `json:"policy"`
 }
 
 type Coordinates struct {
@@ -32,7 +33,17 @@ type Usage struct {
 }
 
 type Plugin struct {
-	Github *GithubPlugin `json:"github"`
+	Github         *GithubPlugin        `json:"github"`
+	Alias          string               `json:"alias"`
+	Settings       map[string]any       `json:"settings"`
+	Env            map[string]string    `json:"env"`
+	PassEnv        []string             `json:"passEnv"`
+	Dir            string               `json:"dir"`
+	MaxPayloadSize int                  `json:"maxPayloadSize"`
+	Limits         jpoet.PluginLimits   `json:"limits"`
+	Timeout        jpoet.PluginTimeouts `json:"timeout"`
+	Retry          jpoet.RetryPolicy    `json:"retry"`
+	Sandbox        *jpoet.PluginSandbox `json:"sandbox"`
 }
 
 type GithubPlugin struct {
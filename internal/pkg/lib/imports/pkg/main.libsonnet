@@ -12,6 +12,7 @@
     source: std.get(pkg, 'source', null),
     description: description,
     plugins: std.get(pkg, 'plugins', []),
+    policy: std.get(pkg, 'policy', {}),
     children: children,
   },
   desc(description, children={}): {
@@ -24,11 +25,11 @@
     children: children,
   },
   plugin: {
-    github(repo, version): {
+    github(repo, version, config={}): {
       github: {
         repo: repo,
         version: version,
       },
-    },
+    } + config,
   },
 }
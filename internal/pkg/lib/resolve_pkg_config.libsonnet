@@ -71,7 +71,7 @@ local merge(lib, pkg, examples) =
       then repo[:std.length(repo) - 4] else repo,
     httpRepo: changeProtocol(removeSuffix(super.repo)),
   };
-  mergeRec(lib, pkg, examples, coordinates, pkg.usage, pkg.source) + { root: true };
+  mergeRec(lib, pkg, examples, coordinates, pkg.usage, pkg.source) + { root: true, policy: std.get(pkg, 'policy', {}) };
 
 local resolvePkgConfig(lib, pkg, examples, examplesString) =
   local injectedExamples = injectExampleString(examples, examplesString);
@@ -0,0 +1,124 @@
+package pkg
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/marcbran/jpoet/pkg/jpoet"
+)
+
+func (p Plugin) Name() string {
+	if p.Alias != "" {
+		return p.Alias
+	}
+	if p.Github != nil {
+		return strings.TrimPrefix(path.Base(p.Github.Repo), "jsonnet-plugin-")
+	}
+	return ""
+}
+
+func (p Plugin) config(pkgDir string) jpoet.PluginConfig {
+	dir := p.Dir
+	if dir != "" && !filepath.IsAbs(dir) {
+		dir = filepath.Join(pkgDir, dir)
+	}
+	var sandbox *jpoet.PluginSandbox
+	if p.Sandbox != nil {
+		sandbox = &jpoet.PluginSandbox{
+			Read:    pkgPaths(pkgDir, p.Sandbox.Read),
+			Write:   pkgPaths(pkgDir, p.Sandbox.Write),
+			Network: p.Sandbox.Network,
+		}
+	}
+	return jpoet.PluginConfig{
+		Settings:       p.Settings,
+		Env:            p.Env,
+		PassEnv:        p.PassEnv,
+		Dir:            dir,
+		MaxPayloadSize: p.MaxPayloadSize,
+		Limits:         p.Limits,
+		Timeouts:       p.Timeout,
+		Retry:          p.Retry,
+		Sandbox:        sandbox,
+	}
+}
+
+func pkgPaths(pkgDir string, paths []string) []string {
+	resolved := make([]string, 0, len(paths))
+	for _, p := range paths {
+		if !filepath.IsAbs(p) {
+			p = filepath.Join(pkgDir, p)
+		}
+		resolved = append(resolved, p)
+	}
+	return resolved
+}
+
+func LoadPkgConfig(pkgDir string) (Config, error) {
+	_, err := os.Stat(filepath.Join(pkgDir, "pkg.libsonnet"))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return Config{}, nil
+		}
+		return Config{}, err
+	}
+	cfg, err := ResolvePkgConfig(pkgDir)
+	if err != nil {
+		return Config{}, err
+	}
+	err = checkPluginNames(cfg.Plugins)
+	if err != nil {
+		return Config{}, err
+	}
+	return cfg, nil
+}
+
+func PluginConfigs(pkgDir string, cfg Config) map[string]jpoet.PluginConfig {
+	configs := make(map[string]jpoet.PluginConfig)
+	for _, p := range cfg.Plugins {
+		name := p.Name()
+		if name == "" {
+			continue
+		}
+		configs[name] = p.config(pkgDir)
+	}
+	return configs
+}
+
+func Policy(cfg Config) (jpoet.PluginPolicy, error) {
+	err := cfg.Policy.Validate()
+	if err != nil {
+		return jpoet.PluginPolicy{}, fmt.Errorf("invalid policy in pkg.libsonnet: %w", err)
+	}
+	return cfg.Policy, nil
+}
+
+func checkPluginNames(plugins []Plugin) error {
+	declared := make(map[string]Plugin)
+	for _, p := range plugins {
+		name := p.Name()
+		if name == "" {
+			continue
+		}
+		if strings.ContainsAny(name, ": \t\n") {
+			return fmt.Errorf("plugin alias %q must not contain colons or whitespace", name)
+		}
+		other, ok := declared[name]
+		if ok {
+			return fmt.Errorf("plugins %s and %s both provide invoke:%s, give one of them an alias", other.describe(), p.describe(), name)
+		}
+		declared[name] = p
+	}
+	return nil
+}
+
+func (p Plugin) describe() string {
+	if p.Github != nil {
+		return p.Github.Repo + " " + p.Github.Version
+	}
+	return p.Name()
+}
@@ -0,0 +1,116 @@
+package pkg
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/marcbran/jpoet/pkg/jpoet"
+)
+
+const PluginPathEnv = "JPOET_PLUGIN_PATH"
+
+func PluginPath() ([]string, error) {
+	var stores []string
+	for _, store := range filepath.SplitList(os.Getenv(PluginPathEnv)) {
+		if store != "" {
+			stores = append(stores, store)
+		}
+	}
+	if len(stores) > 0 {
+		return stores, nil
+	}
+	cacheDir, err := os.UserCacheDir()
+	if err != nil {
+		return nil, fmt.Errorf("failed to find the plugin store, set %s instead: %w", PluginPathEnv, err)
+	}
+	return []string{filepath.Join(cacheDir, "jpoet", "plugins")}, nil
+}
+
+func (p GithubPlugin) storeDir(store string) string {
+	return filepath.Join(store, filepath.FromSlash(p.Repo), p.Version)
+}
+
+func (p GithubPlugin) find(stores []string) (string, bool, error) {
+	for _, store := range stores {
+		dir := p.storeDir(store)
+		manifest, err := jpoet.ReadPluginManifest(dir)
+		if err != nil {
+			return "", false, err
+		}
+		if manifest == nil {
+			continue
+		}
+		err = p.check(dir, *manifest)
+		if err != nil {
+			return "", false, err
+		}
+		return dir, true, nil
+	}
+	return "", false, nil
+}
+
+func (p GithubPlugin) writeManifest(pluginDir string) error {
+	repo := path.Base(p.Repo)
+	sum, err := jpoet.PluginSHA256(filepath.Join(pluginDir, repo))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("plugin %s %s does not contain the executable %s", p.Repo, p.Version, repo)
+		}
+		return err
+	}
+	return jpoet.PluginManifest{
+		Name:       repo,
+		NativeName: strings.TrimPrefix(repo, "jsonnet-plugin-"),
+		Version:    p.Version,
+		Source:     p.source(),
+		SHA256:     sum,
+	}.Write(pluginDir)
+}
+
+func (p GithubPlugin) source() string {
+	return "github.com/" + p.Repo
+}
+
+func (p GithubPlugin) check(dir string, manifest jpoet.PluginManifest) error {
+	if manifest.Source != p.source() {
+		return fmt.Errorf("plugin in %s has been installed from %s according to its manifest, but pkg.libsonnet declares %s", dir, manifest.Source, p.source())
+	}
+	if manifest.Version != p.Version {
+		return fmt.Errorf("plugin in %s is version %s according to its manifest, but pkg.libsonnet declares %s", dir, manifest.Version, p.Version)
+	}
+	return nil
+}
+
+func PluginDirs(pkgDir string, cfg Config) (map[string]string, map[string]error, error) {
+	dirs := make(map[string]string)
+	missing := make(map[string]error)
+	stores, err := PluginPath()
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, p := range cfg.Plugins {
+		if p.Github == nil {
+			continue
+		}
+		dir, ok, err := p.Github.find(stores)
+		if err != nil {
+			return nil, nil, err
+		}
+		if ok {
+			dirs[p.Name()] = dir
+			continue
+		}
+		if p.Alias == "" {
+			_, err = os.Stat(filepath.Join(pkgDir, ".jpoet", "plugins", path.Base(p.Github.Repo)))
+			if err == nil {
+				continue
+			}
+		}
+		missing[p.Name()] = fmt.Errorf("plugin %s %s is not installed, run jpoet install to install it", p.Github.Repo, p.Github.Version)
+	}
+	return dirs, missing, nil
+}
@@ -3,62 +3,209 @@ package plugin
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"os/exec"
+	"io"
 	"path/filepath"
+	"regexp"
+	"strconv"
 	"strings"
+	"sync/atomic"
+	"time"
 
 	"github.com/hashicorp/go-plugin"
 	"github.com/marcbran/jpoet/internal/plugin/proto"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 type client struct {
-	invoker Invoker
-	client  *plugin.Client
+	invoker   *grpcClientInvoker
+	client    *plugin.Client
+	limiter   *limiter
+	version   int
+	functions []FunctionInfo
+	broken    atomic.Bool
 }
 
 func NewClientInvoker(
 	name string,
 	path string,
+	config ProcessConfig,
 ) (InvokeCloser, error) {
-	base := filepath.Base(path)
-	if !strings.HasPrefix(base, "jsonnet-plugin-") {
-		return nil, fmt.Errorf("plugin path does not start with jsonnet-plugin")
+	err := checkPluginPath(path)
+	if err != nil {
+		return nil, err
 	}
-	baseName := strings.TrimPrefix(base, "jsonnet-plugin-")
-	if baseName != name {
-		return nil, fmt.Errorf("plugin name does not match path")
+	return newSupervisor(name, func(stderr io.Writer) (process, error) {
+		err := verifyChecksum(name, path, config.SHA256)
+		if err != nil {
+			return nil, err
+		}
+		clientConfig := &plugin.ClientConfig{
+			SkipHostEnv: true,
+			Stderr:      stderr,
+			SyncStderr:  stderr,
+		}
+		cmd, err := config.command(name, path)
+		if err != nil {
+			return nil, err
+		}
+		if !config.confined() {
+			clientConfig.Cmd = cmd
+			return startClient(name, clientConfig, config, nil)
+		}
+		l, err := newLimiter(name, config.Limits)
+		if err != nil {
+			return nil, err
+		}
+		clientConfig.RunnerFunc = limitedRunnerFunc(name, path, cmd, l)
+		return startClient(name, clientConfig, config, l)
+	}), nil
+}
+
+func NewReattachInvoker(
+	name string,
+	reattachConfig ReattachConfig,
+	config ProcessConfig,
+) (InvokeCloser, error) {
+	reattach, err := reattachConfig.pluginConfig()
+	if err != nil {
+		return nil, fmt.Errorf("invalid reattach configuration for plugin %s: %w", name, err)
 	}
+	return newSupervisor(name, func(stderr io.Writer) (process, error) {
+		return startClient(name, &plugin.ClientConfig{
+			Reattach:   reattach,
+			Stderr:     stderr,
+			SyncStderr: stderr,
+		}, config, nil)
+	}), nil
+}
 
-	pluginClient := plugin.NewClient(&plugin.ClientConfig{
-		HandshakeConfig: handshakeConfig,
-		Plugins: map[string]plugin.Plugin{
-			"invoker": &grpcPlugin{},
-		},
-		Cmd:              exec.Command(path),
-		AllowedProtocols: []plugin.Protocol{plugin.ProtocolGRPC},
-		Logger:           newLogger(),
-	})
+func startClient(name string, config *plugin.ClientConfig, processConfig ProcessConfig, l *limiter) (process, error) {
+	settings := processConfig.Settings
+	config.HandshakeConfig = handshakeConfig
+	config.VersionedPlugins = versionedPlugins(&grpcPlugin{})
+	config.AllowedProtocols = []plugin.Protocol{plugin.ProtocolGRPC}
+	config.Logger = newLogger()
+	config.GRPCDialOptions = processConfig.dialOptions()
+	pluginClient := plugin.NewClient(config)
 
 	rpcClient, err := pluginClient.Client()
 	if err != nil {
-		return nil, err
+		pluginClient.Kill()
+		return nil, versionError(name, err)
 	}
+	version := pluginClient.NegotiatedVersion()
 
 	raw, err := rpcClient.Dispense("invoker")
 	if err != nil {
+		pluginClient.Kill()
 		return nil, err
 	}
-	invoker := raw.(Invoker)
+	invoker := raw.(*grpcClientInvoker)
+	invoker.name = name
+	invoker.version = version
+	invoker.maxPayloadSize = processConfig.maxPayloadSize()
+
+	if settings != nil {
+		err = invoker.Configure(settings)
+		if err != nil {
+			pluginClient.Kill()
+			if status.Code(err) == codes.Unimplemented {
+				return nil, fmt.Errorf(
+					"plugin %s uses protocol version %d and does not support settings, which require protocol version %d, it needs to be rebuilt with a newer version of jpoet",
+					name, version, maxProtocolVersion,
+				)
+			}
+			return nil, fmt.Errorf("failed to configure plugin %s: %w", name, err)
+		}
+	}
+
+	var functions []FunctionInfo
+	if version >= 2 {
+		functions, err = invoker.Describe()
+		if err != nil {
+			pluginClient.Kill()
+			return nil, fmt.Errorf("failed to describe plugin %s: %w", name, err)
+		}
+	}
 
 	return &client{
-		invoker: invoker,
-		client:  pluginClient,
+		invoker:   invoker,
+		client:    pluginClient,
+		limiter:   l,
+		version:   version,
+		functions: functions,
 	}, nil
 }
 
+var incompatibleVersionPattern = regexp.MustCompile(`Plugin version: (\d+), Client versions: \[([\d ]*)\]`)
+
+func versionError(name string, err error) error {
+	match := incompatibleVersionPattern.FindStringSubmatch(err.Error())
+	if match == nil {
+		return err
+	}
+	pluginVersion, convErr := strconv.Atoi(match[1])
+	if convErr != nil {
+		return err
+	}
+	supported := fmt.Sprintf("%d to %d", minProtocolVersion, maxProtocolVersion)
+	if pluginVersion > maxProtocolVersion {
+		return fmt.Errorf(
+			"plugin %s uses protocol version %d, but this version of jpoet only supports protocol versions %s, jpoet needs to be upgraded",
+			name, pluginVersion, supported,
+		)
+	}
+	return fmt.Errorf(
+		"plugin %s uses protocol version %d, but this version of jpoet only supports protocol versions %s, the plugin needs to be rebuilt with a newer version of jpoet",
+		name, pluginVersion, supported,
+	)
+}
+
+func checkPluginPath(path string) error {
+	base := filepath.Base(path)
+	if !strings.HasPrefix(base, "jsonnet-plugin-") {
+		return fmt.Errorf("plugin path %s does not start with jsonnet-plugin-", path)
+	}
+	return nil
+}
+
 func (c *client) Invoke(funcName string, args []any) (any, error) {
-	return c.invoker.Invoke(funcName, args)
+	return c.InvokeWithHost(nil, funcName, args)
+}
+
+func (c *client) InvokeWithHost(host Host, funcName string, args []any) (any, error) {
+	res, err := c.invoker.InvokeWithHost(host, funcName, args)
+	if status.Code(err) == codes.Unavailable {
+		c.broken.Store(true)
+	}
+	return res, err
+}
+
+func (c *client) InvokeBatch(calls []Call) ([]Result, error) {
+	return c.InvokeBatchWithHost(nil, calls)
+}
+
+func (c *client) InvokeBatchWithHost(host Host, calls []Call) ([]Result, error) {
+	results, err := c.invoker.InvokeBatchWithHost(host, calls)
+	if status.Code(err) == codes.Unavailable {
+		c.broken.Store(true)
+	}
+	return results, err
+}
+
+func (c *client) Release(handles []string) error {
+	return c.invoker.Release(handles)
+}
+
+func (c *client) Functions() []FunctionInfo {
+	return c.functions
+}
+
+func (c *client) Exited() bool {
+	return c.broken.Load() || c.client.Exited()
 }
 
 func (c *client) Close() error {
@@ -66,21 +213,76 @@ func (c *client) Close() error {
 	return nil
 }
 
+func (c *client) limitExceeded() string {
+	if c.limiter == nil {
+		return ""
+	}
+	return c.limiter.limitExceeded()
+}
+
 type grpcClientInvoker struct {
-	client proto.InvokerClient
+	client         proto.InvokerClient
+	hosts          *hostRegistry
+	name           string
+	version        int
+	maxPayloadSize int
+}
+
+type hostRef struct {
+	id    uint32
+	scope uint64
+}
+
+func (c grpcClientInvoker) registerHost(host Host) (hostRef, func()) {
+	if host == nil || c.version < 5 {
+		return hostRef{}, func() {}
+	}
+	id, scope := c.hosts.register(host)
+	return hostRef{id: id, scope: scope}, func() {
+		c.hosts.unregister(scope)
+	}
 }
 
 func (c grpcClientInvoker) Invoke(funcName string, args []any) (any, error) {
+	return c.InvokeWithHost(nil, funcName, args)
+}
+
+func (c grpcClientInvoker) InvokeWithHost(host Host, funcName string, args []any) (any, error) {
 	b, err := json.Marshal(args)
 	if err != nil {
 		return nil, err
 	}
+	if len(b) > c.maxPayloadSize {
+		return nil, payloadSizeError{
+			plugin:  c.name,
+			call:    funcName,
+			payload: "argument list",
+			size:    len(b),
+			limit:   c.maxPayloadSize,
+		}
+	}
+	ref, release := c.registerHost(host)
+	defer release()
+	if c.version >= 4 && len(b) > streamChunkSize {
+		return c.invokeStream(funcName, b, ref)
+	}
 	resp, err := c.client.Invoke(context.Background(), &proto.InvokeRequest{
-		FuncName: funcName,
-		Args:     b,
+		FuncName:  funcName,
+		Args:      b,
+		HostId:    ref.id,
+		HostScope: ref.scope,
 	})
 	if err != nil {
-		return nil, err
+		return nil, statusError(c.sizeError(funcName, err))
+	}
+	if len(resp.Value) > c.maxPayloadSize {
+		return nil, payloadSizeError{
+			plugin:  c.name,
+			call:    funcName,
+			payload: "result",
+			size:    len(resp.Value),
+			limit:   c.maxPayloadSize,
+		}
 	}
 	var res any
 	err = json.Unmarshal(resp.Value, &res)
@@ -89,3 +291,153 @@ func (c grpcClientInvoker) Invoke(funcName string, args []any) (any, error) {
 	}
 	return res, nil
 }
+
+func (c grpcClientInvoker) InvokeBatch(calls []Call) ([]Result, error) {
+	return c.InvokeBatchWithHost(nil, calls)
+}
+
+func (c grpcClientInvoker) InvokeBatchWithHost(host Host, calls []Call) ([]Result, error) {
+	if c.version < 3 {
+		results := make([]Result, len(calls))
+		for i, call := range calls {
+			value, err := c.InvokeWithHost(host, call.FuncName, call.Args)
+			if status.Code(err) == codes.Unavailable {
+				return nil, err
+			}
+			results[i] = Result{Value: value, Err: err}
+		}
+		return results, nil
+	}
+	ref, release := c.registerHost(host)
+	defer release()
+	results := make([]Result, len(calls))
+	args := make([][]byte, len(calls))
+	var batches [][]int
+	var batch []int
+	size := 0
+	for i, call := range calls {
+		b, err := json.Marshal(call.Args)
+		if err != nil {
+			return nil, err
+		}
+		if len(b) > c.maxPayloadSize {
+			results[i] = Result{Err: payloadSizeError{
+				plugin:  c.name,
+				call:    call.FuncName,
+				payload: "argument list",
+				size:    len(b),
+				limit:   c.maxPayloadSize,
+			}}
+			continue
+		}
+		if c.version >= 4 && len(b) > streamChunkSize {
+			value, err := c.invokeStream(call.FuncName, b, ref)
+			if status.Code(err) == codes.Unavailable {
+				return nil, err
+			}
+			results[i] = Result{Value: value, Err: err}
+			continue
+		}
+		if len(batch) > 0 && size+len(b) > c.maxPayloadSize {
+			batches = append(batches, batch)
+			batch, size = nil, 0
+		}
+		args[i] = b
+		batch = append(batch, i)
+		size += len(b)
+	}
+	if len(batch) > 0 {
+		batches = append(batches, batch)
+	}
+	for _, batch := range batches {
+		err := c.invokeBatch(calls, args, batch, ref, results)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return results, nil
+}
+
+func (c grpcClientInvoker) invokeBatch(calls []Call, args [][]byte, indices []int, ref hostRef, results []Result) error {
+	req := &proto.InvokeBatchRequest{
+		Calls:     make([]*proto.InvokeRequest, len(indices)),
+		HostId:    ref.id,
+		HostScope: ref.scope,
+	}
+	for j, i := range indices {
+		req.Calls[j] = &proto.InvokeRequest{
+			FuncName: calls[i].FuncName,
+			Args:     args[i],
+		}
+	}
+	resp, err := c.client.InvokeBatch(context.Background(), req)
+	if err != nil {
+		err = c.sizeError(fmt.Sprintf("a batch of %d calls", len(indices)), err)
+		var sizeErr payloadSizeError
+		if !errors.As(err, &sizeErr) {
+			return err
+		}
+		for _, i := range indices {
+			results[i] = Result{Err: err}
+		}
+		return nil
+	}
+	if len(resp.Results) != len(indices) {
+		return fmt.Errorf("plugin returned %d results for %d calls", len(resp.Results), len(indices))
+	}
+	for j, r := range resp.Results {
+		i := indices[j]
+		if r.Error != "" {
+			results[i] = Result{Err: remoteError(r.Error, r.Retryable)}
+			continue
+		}
+		var value any
+		err = json.Unmarshal(r.Value, &value)
+		if err != nil {
+			return err
+		}
+		results[i] = Result{Value: value}
+	}
+	return nil
+}
+
+func (c grpcClientInvoker) Release(handles []string) error {
+	if c.version < 6 {
+		return nil
+	}
+	_, err := c.client.Release(context.Background(), &proto.ReleaseRequest{
+		Handles: handles,
+	})
+	return err
+}
+
+func (c grpcClientInvoker) Configure(settings map[string]any) error {
+	b, err := json.Marshal(settings)
+	if err != nil {
+		return err
+	}
+	_, err = c.client.Configure(context.Background(), &proto.ConfigureRequest{
+		Settings: b,
+	})
+	return err
+}
+
+func (c grpcClientInvoker) Describe() ([]FunctionInfo, error) {
+	resp, err := c.client.Describe(context.Background(), &proto.DescribeRequest{})
+	if err != nil {
+		return nil, err
+	}
+	var functions []FunctionInfo
+	for _, f := range resp.Functions {
+		functions = append(functions, FunctionInfo{
+			Name:   f.Name,
+			Params: f.Params,
+			Cache: CacheHint{
+				Pure:  f.Cache.GetPure(),
+				TTL:   time.Duration(f.Cache.GetTtlMillis()) * time.Millisecond,
+				Never: f.Cache.GetNever(),
+			},
+		})
+	}
+	return functions, nil
+}
@@ -0,0 +1,516 @@
+package plugin
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-jsonnet"
+	"github.com/google/go-jsonnet/ast"
+	"github.com/hashicorp/go-plugin"
+	"github.com/marcbran/jpoet/internal/plugin/proto"
+	"google.golang.org/grpc"
+)
+
+func TestVersionError(t *testing.T) {
+	supported := fmt.Sprintf("%d to %d", minProtocolVersion, maxProtocolVersion)
+	tests := []struct {
+		name     string
+		err      error
+		expected string
+	}{
+		{
+			name:     "newer plugin",
+			err:      errors.New("incompatible API version with plugin. Plugin version: 99, Client versions: [1 2]"),
+			expected: "plugin test uses protocol version 99, but this version of jpoet only supports protocol versions " + supported + ", jpoet needs to be upgraded",
+		},
+		{
+			name:     "older plugin",
+			err:      errors.New("incompatible API version with plugin. Plugin version: 0, Client versions: [1 2]"),
+			expected: "plugin test uses protocol version 0, but this version of jpoet only supports protocol versions " + supported + ", the plugin needs to be rebuilt with a newer version of jpoet",
+		},
+		{
+			name:     "other error",
+			err:      errors.New("plugin exited before we could connect"),
+			expected: "plugin exited before we could connect",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := versionError("test", test.err)
+			if !strings.Contains(err.Error(), test.expected) {
+				t.Errorf("expected %q, got %q", test.expected, err.Error())
+			}
+		})
+	}
+}
+
+var leakedHost Host
+
+type testResource struct {
+	name string
+}
+
+var closedResources []string
+
+func (r *testResource) Close() error {
+	closedResources = append(closedResources, r.name)
+	return nil
+}
+
+type testHost struct {
+	files map[string]string
+}
+
+func (h testHost) Evaluate(filename string, snippet string) (any, error) {
+	return map[string]any{"filename": filename, "snippet": snippet}, nil
+}
+
+func (h testHost) Import(importedFrom string, importedPath string) (string, string, error) {
+	contents, ok := h.files[importedPath]
+	if !ok {
+		return "", "", fmt.Errorf("couldn't open import %q", importedPath)
+	}
+	return contents, importedPath, nil
+}
+
+const grpcTestEnv = "JPOET_TEST_GRPC_PLUGIN"
+
+func serveGRPCTestPlugin() {
+	NewConsumer("test", NewLocalInvoker([]jsonnet.NativeFunction{
+		{
+			Name:   "read",
+			Params: ast.Identifiers{"path"},
+			Func: func(args []any) (any, error) {
+				b, err := os.ReadFile(args[0].(string))
+				if err != nil {
+					return nil, err
+				}
+				return string(b), nil
+			},
+		},
+	})).Serve()
+}
+
+func newConfiguredGRPCProcessTestInvoker(t *testing.T, config ProcessConfig) InvokeCloser {
+	t.Helper()
+	config.PassEnv = append(config.PassEnv, grpcTestEnv)
+	t.Setenv(grpcTestEnv, "1")
+	exe, err := os.Executable()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	path := filepath.Join(t.TempDir(), "jsonnet-plugin-test")
+	err = os.Symlink(exe, path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	invoker, err := NewClientInvoker("test", path, config)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	t.Cleanup(func() {
+		_ = invoker.Close()
+	})
+	return invoker
+}
+
+func newGRPCTestInvoker(t testing.TB) *grpcClientInvoker {
+	return newVersionedGRPCTestInvoker(t, maxProtocolVersion)
+}
+
+func newVersionedGRPCTestInvoker(t testing.TB, version int) *grpcClientInvoker {
+	t.Helper()
+	client, _ := plugin.TestPluginGRPCConn(t, false, map[string]plugin.Plugin{
+		"invoker": &grpcPlugin{
+			Name: "test",
+			Impl: NewLocalHostInvoker([]jsonnet.NativeFunction{
+				{
+					Name:   "upper",
+					Params: ast.Identifiers{"s"},
+					Func: func(args []any) (any, error) {
+						s, ok := args[0].(string)
+						if !ok {
+							return nil, fmt.Errorf("s must be a string")
+						}
+						return strings.ToUpper(s), nil
+					},
+				},
+				{
+					Name:   "open",
+					Params: ast.Identifiers{"name"},
+					Func: func(args []any) (any, error) {
+						return NewHandle(&testResource{name: args[0].(string)}), nil
+					},
+				},
+				{
+					Name:   "name",
+					Params: ast.Identifiers{"resource"},
+					Func: func(args []any) (any, error) {
+						return args[0].(*testResource).name, nil
+					},
+				},
+				{
+					Name:   "busy",
+					Params: ast.Identifiers{},
+					Func: func(args []any) (any, error) {
+						return nil, Retryable(errors.New("busy"))
+					},
+				},
+				{
+					Name:   "repeat",
+					Params: ast.Identifiers{"s", "n"},
+					Func: func(args []any) (any, error) {
+						return strings.Repeat(args[0].(string), int(args[1].(float64))), nil
+					},
+				},
+			}, []HostFunction{
+				{
+					Name:   "evaluate",
+					Params: ast.Identifiers{"snippet"},
+					Func: func(host Host, args []any) (any, error) {
+						return host.Evaluate("snippet.jsonnet", args[0].(string))
+					},
+				},
+				{
+					Name:   "import",
+					Params: ast.Identifiers{"path"},
+					Func: func(host Host, args []any) (any, error) {
+						contents, _, err := host.Import("main.jsonnet", args[0].(string))
+						return contents, err
+					},
+				},
+				{
+					Name:   "leak",
+					Params: ast.Identifiers{},
+					Func: func(host Host, args []any) (any, error) {
+						leakedHost = host
+						return nil, nil
+					},
+				},
+			}),
+			CacheHints: map[string]CacheHint{
+				"upper": {Pure: true},
+				"name":  {TTL: time.Minute},
+				"open":  {Never: true},
+			},
+		},
+	})
+	t.Cleanup(func() {
+		_ = client.Close()
+	})
+	raw, err := client.Dispense("invoker")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	invoker := raw.(*grpcClientInvoker)
+	invoker.name = "test"
+	invoker.version = version
+	invoker.maxPayloadSize = DefaultMaxPayloadSize
+	return invoker
+}
+
+func TestGRPCInvoker_InvokeBatch(t *testing.T) {
+	invoker := newGRPCTestInvoker(t)
+
+	results, err := invoker.InvokeBatch([]Call{
+		{FuncName: "upper", Args: []any{"a"}},
+		{FuncName: "upper", Args: []any{1.0}},
+		{FuncName: "missing", Args: []any{}},
+		{FuncName: "upper", Args: []any{"b"}},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(results) != 4 {
+		t.Fatalf("expected 4 results, got %d", len(results))
+	}
+	if results[0].Value != "A" || results[3].Value != "B" {
+		t.Errorf("unexpected results: %v", results)
+	}
+	if results[1].Err == nil || !strings.Contains(results[1].Err.Error(), "s must be a string") {
+		t.Errorf("expected argument error, got: %v", results[1].Err)
+	}
+	if results[2].Err == nil || !strings.Contains(results[2].Err.Error(), "no such function") {
+		t.Errorf("expected missing function error, got: %v", results[2].Err)
+	}
+}
+
+func TestGRPCInvoker_Describe(t *testing.T) {
+	invoker := newGRPCTestInvoker(t)
+
+	functions, err := invoker.Describe()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var names []string
+	for _, f := range functions {
+		names = append(names, f.Name)
+	}
+	if strings.Join(names, ",") != "busy,evaluate,import,leak,name,open,repeat,upper" {
+		t.Errorf("unexpected functions: %v", names)
+	}
+	if len(functions[7].Params) != 1 || functions[7].Params[0] != "s" {
+		t.Errorf("unexpected functions: %v", functions)
+	}
+	if !functions[7].Cache.Pure || functions[4].Cache.TTL != time.Minute || !functions[5].Cache.Never || functions[1].Cache != (CacheHint{}) {
+		t.Errorf("unexpected cache hints: %v", functions)
+	}
+}
+
+func TestGRPCInvoker_InvokeLargePayload(t *testing.T) {
+	invoker := newGRPCTestInvoker(t)
+	large := strings.Repeat("a", 3*streamChunkSize+17)
+
+	res, err := invoker.Invoke("upper", []any{large})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if res != strings.ToUpper(large) {
+		t.Errorf("unexpected result of %d bytes", len(fmt.Sprint(res)))
+	}
+
+	_, err = invoker.Invoke("upper", []any{1.0})
+	if err == nil || !strings.Contains(err.Error(), "s must be a string") {
+		t.Errorf("expected argument error, got: %v", err)
+	}
+}
+
+type streamCountingClient struct {
+	proto.InvokerClient
+	streams int
+}
+
+func (c *streamCountingClient) InvokeStream(ctx context.Context, opts ...grpc.CallOption) (grpc.BidiStreamingClient[proto.InvokeChunk, proto.InvokeChunk], error) {
+	c.streams++
+	return c.InvokerClient.InvokeStream(ctx, opts...)
+}
+
+func TestGRPCInvoker_StreamsOnlyLargeArguments(t *testing.T) {
+	invoker := newGRPCTestInvoker(t)
+	client := &streamCountingClient{InvokerClient: invoker.client}
+	invoker.client = client
+
+	res, err := invoker.Invoke("upper", []any{"a"})
+	if err != nil || res != "A" {
+		t.Fatalf("unexpected result: %v, %v", res, err)
+	}
+	if client.streams != 0 {
+		t.Errorf("expected small arguments to be sent unary, got %d streams", client.streams)
+	}
+
+	large := strings.Repeat("a", streamChunkSize+1)
+	res, err = invoker.Invoke("upper", []any{large})
+	if err != nil || res != strings.ToUpper(large) {
+		t.Fatalf("unexpected result of %d bytes: %v", len(fmt.Sprint(res)), err)
+	}
+	if client.streams != 1 {
+		t.Errorf("expected large arguments to be streamed, got %d streams", client.streams)
+	}
+}
+
+func TestGRPCInvoker_Retryable(t *testing.T) {
+	for _, version := range []int{3, maxProtocolVersion} {
+		t.Run(fmt.Sprintf("version %d", version), func(t *testing.T) {
+			invoker := newVersionedGRPCTestInvoker(t, version)
+
+			_, err := invoker.Invoke("busy", []any{})
+			if err == nil || !IsRetryable(err) || err.Error() != "busy" {
+				t.Errorf("expected retryable error, got: %v", err)
+			}
+			_, err = invoker.Invoke("upper", []any{1.0})
+			if err == nil || IsRetryable(err) {
+				t.Errorf("expected error not to be retryable, got: %v", err)
+			}
+		})
+	}
+}
+
+func TestGRPCInvoker_PayloadLimit(t *testing.T) {
+	for _, version := range []int{3, maxProtocolVersion} {
+		t.Run(fmt.Sprintf("version %d", version), func(t *testing.T) {
+			invoker := newVersionedGRPCTestInvoker(t, version)
+			invoker.maxPayloadSize = 1000
+
+			_, err := invoker.Invoke("upper", []any{strings.Repeat("a", 2000)})
+			if err == nil || !strings.Contains(err.Error(), "argument list of 2004 bytes exceeds the limit of 1000 bytes when invoking upper of plugin test") {
+				t.Errorf("expected argument size error, got: %v", err)
+			}
+		})
+	}
+}
+
+func TestGRPCInvoker_BatchPayloadLimit(t *testing.T) {
+	for _, version := range []int{3, maxProtocolVersion} {
+		t.Run(fmt.Sprintf("version %d", version), func(t *testing.T) {
+			invoker := newVersionedGRPCTestInvoker(t, version)
+			invoker.maxPayloadSize = 1000
+
+			calls := []Call{{FuncName: "upper", Args: []any{strings.Repeat("a", 2000)}}}
+			for range 5 {
+				calls = append(calls, Call{FuncName: "upper", Args: []any{strings.Repeat("b", 300)}})
+			}
+			results, err := invoker.InvokeBatch(calls)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if results[0].Err == nil || !strings.Contains(results[0].Err.Error(), "argument list of 2004 bytes exceeds the limit of 1000 bytes when invoking upper of plugin test") {
+				t.Errorf("expected argument size error, got: %v", results[0].Err)
+			}
+			for _, r := range results[1:] {
+				if r.Err != nil || r.Value != strings.Repeat("B", 300) {
+					t.Errorf("unexpected result: %v", r)
+				}
+			}
+		})
+	}
+}
+
+func TestGRPCInvoker_BatchLargePayload(t *testing.T) {
+	invoker := newGRPCTestInvoker(t)
+	large := strings.Repeat("a", 3*streamChunkSize+17)
+
+	results, err := invoker.InvokeBatch([]Call{
+		{FuncName: "upper", Args: []any{"a"}},
+		{FuncName: "upper", Args: []any{large}},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if results[0].Value != "A" || results[1].Value != strings.ToUpper(large) {
+		t.Errorf("unexpected results: %v, result of %d bytes", results[0], len(fmt.Sprint(results[1].Value)))
+	}
+}
+
+func TestGRPCInvoker_ResultLimit(t *testing.T) {
+	invoker := newGRPCTestInvoker(t)
+	invoker.maxPayloadSize = 2 * streamChunkSize
+
+	_, err := invoker.Invoke("repeat", []any{"a", 3.0 * streamChunkSize})
+	if err == nil || !strings.Contains(err.Error(), fmt.Sprintf("result of %d bytes exceeds the limit of %d bytes when invoking repeat", 3*streamChunkSize+2, 2*streamChunkSize)) {
+		t.Errorf("expected result size error, got: %v", err)
+	}
+}
+
+func TestGRPCInvoker_Host(t *testing.T) {
+	invoker := newGRPCTestInvoker(t)
+	host := testHost{files: map[string]string{"lib.libsonnet": "{ a: 1 }"}}
+
+	res, err := invoker.InvokeWithHost(host, "import", []any{"lib.libsonnet"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if res != "{ a: 1 }" {
+		t.Errorf("unexpected import: %v", res)
+	}
+
+	_, err = invoker.InvokeWithHost(host, "import", []any{"missing.libsonnet"})
+	if err == nil || !strings.Contains(err.Error(), `couldn't open import "missing.libsonnet"`) {
+		t.Errorf("expected import error, got: %v", err)
+	}
+
+	results, err := invoker.InvokeBatchWithHost(host, []Call{{FuncName: "evaluate", Args: []any{"1 + 1"}}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	value, ok := results[0].Value.(map[string]any)
+	if !ok || value["snippet"] != "1 + 1" {
+		t.Errorf("unexpected evaluation: %v", results[0])
+	}
+
+	_, err = invoker.Invoke("import", []any{"lib.libsonnet"})
+	if err == nil || !strings.Contains(err.Error(), "invoked without one") {
+		t.Errorf("expected missing host error, got: %v", err)
+	}
+}
+
+func TestGRPCInvoker_HostScope(t *testing.T) {
+	invoker := newGRPCTestInvoker(t)
+
+	_, err := invoker.InvokeWithHost(testHost{}, "leak", []any{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	_, err = leakedHost.Evaluate("snippet.jsonnet", "1")
+	if err == nil || !strings.Contains(err.Error(), "is not active") {
+		t.Errorf("expected inactive scope error, got: %v", err)
+	}
+}
+
+func TestGRPCInvoker_Handles(t *testing.T) {
+	invoker := newGRPCTestInvoker(t)
+	closedResources = nil
+
+	handle, err := invoker.Invoke("open", []any{"db"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	id, ok := HandleID(handle)
+	if !ok {
+		t.Fatalf("expected handle, got: %v", handle)
+	}
+
+	res, err := invoker.Invoke("name", []any{handle})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if res != "db" {
+		t.Errorf("unexpected result: %v", res)
+	}
+
+	other := newGRPCTestInvoker(t)
+	_, err = other.Invoke("open", []any{"other"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	_, err = other.Invoke("name", []any{handle})
+	if err == nil || !strings.Contains(err.Error(), "unknown handle "+id) {
+		t.Errorf("expected handle to be unknown to another plugin process, got: %v", err)
+	}
+
+	err = invoker.Release([]string{id})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if strings.Join(closedResources, ",") != "db" {
+		t.Errorf("expected resource to be closed, got: %v", closedResources)
+	}
+
+	_, err = invoker.Invoke("name", []any{handle})
+	if err == nil || !strings.Contains(err.Error(), "unknown handle "+id) {
+		t.Errorf("expected unknown handle error, got: %v", err)
+	}
+}
+
+const benchmarkBatchSize = 100
+
+func BenchmarkGRPCInvoker_Invoke(b *testing.B) {
+	invoker := newGRPCTestInvoker(b)
+	for b.Loop() {
+		for range benchmarkBatchSize {
+			_, err := invoker.Invoke("upper", []any{"hello"})
+			if err != nil {
+				b.Fatalf("unexpected error: %v", err)
+			}
+		}
+	}
+}
+
+func BenchmarkGRPCInvoker_InvokeBatch(b *testing.B) {
+	invoker := newGRPCTestInvoker(b)
+	calls := make([]Call, benchmarkBatchSize)
+	for i := range calls {
+		calls[i] = Call{FuncName: "upper", Args: []any{"hello"}}
+	}
+	for b.Loop() {
+		_, err := invoker.InvokeBatch(calls)
+		if err != nil {
+			b.Fatalf("unexpected error: %v", err)
+		}
+	}
+}
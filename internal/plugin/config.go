@@ -0,0 +1,74 @@
+package plugin
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+)
+
+var DefaultPassEnv = []string{"PATH", "HOME", "TMPDIR", "TMP", "TEMP", "SYSTEMROOT", "JP_LOG"}
+
+type ProcessConfig struct {
+	Settings       map[string]any
+	Env            map[string]string
+	PassEnv        []string
+	Dir            string
+	MaxPayloadSize int
+	Limits         Limits
+	Sandbox        *Sandbox
+	Timeouts       Timeouts
+	Retry          RetryPolicy
+	SHA256         string
+}
+
+func (c ProcessConfig) confined() bool {
+	return !c.Limits.IsZero() || c.Sandbox != nil
+}
+
+func (c ProcessConfig) command(name string, path string) (*exec.Cmd, error) {
+	if c.Dir != "" {
+		abs, err := filepath.Abs(path)
+		if err == nil {
+			path = abs
+		}
+	}
+	cmd := exec.Command(path)
+	cmd.Env = c.environ()
+	cmd.Dir = c.Dir
+	if c.Sandbox != nil {
+		err := c.Sandbox.confine(name, cmd)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return cmd, nil
+}
+
+func (c ProcessConfig) environ() []string {
+	passEnv := c.PassEnv
+	if passEnv == nil {
+		passEnv = DefaultPassEnv
+	}
+	env := make(map[string]string)
+	for _, key := range passEnv {
+		value, ok := os.LookupEnv(key)
+		if ok {
+			env[key] = value
+		}
+	}
+	for key, value := range c.Env {
+		env[key] = value
+	}
+	keys := make([]string, 0, len(env))
+	for key := range env {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	environ := make([]string, 0, len(keys))
+	for _, key := range keys {
+		environ = append(environ, fmt.Sprintf("%s=%s", key, env[key]))
+	}
+	return environ
+}
@@ -0,0 +1,106 @@
+package plugin
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"os/signal"
+
+	"github.com/hashicorp/go-plugin"
+)
+
+const (
+	DebugEnv    = "JPOET_PLUGIN_DEBUG"
+	ReattachEnv = "JPOET_REATTACH_PLUGINS"
+)
+
+type ReattachConfig struct {
+	Protocol        string       `json:"protocol"`
+	ProtocolVersion int          `json:"protocolVersion"`
+	Pid             int          `json:"pid"`
+	Test            bool         `json:"test"`
+	Addr            ReattachAddr `json:"addr"`
+}
+
+type ReattachAddr struct {
+	Network string `json:"network"`
+	String  string `json:"string"`
+}
+
+func ParseReattachConfigs(s string) (map[string]ReattachConfig, error) {
+	var configs map[string]ReattachConfig
+	err := json.Unmarshal([]byte(s), &configs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse reattach configuration: %w", err)
+	}
+	return configs, nil
+}
+
+func newReattachConfig(c *plugin.ReattachConfig) ReattachConfig {
+	return ReattachConfig{
+		Protocol:        string(c.Protocol),
+		ProtocolVersion: c.ProtocolVersion,
+		Pid:             c.Pid,
+		Test:            c.Test,
+		Addr: ReattachAddr{
+			Network: c.Addr.Network(),
+			String:  c.Addr.String(),
+		},
+	}
+}
+
+func (c ReattachConfig) pluginConfig() (*plugin.ReattachConfig, error) {
+	var addr net.Addr
+	var err error
+	switch c.Addr.Network {
+	case "unix":
+		addr, err = net.ResolveUnixAddr("unix", c.Addr.String)
+	case "tcp":
+		addr, err = net.ResolveTCPAddr("tcp", c.Addr.String)
+	default:
+		return nil, fmt.Errorf("unsupported reattach network %q", c.Addr.Network)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &plugin.ReattachConfig{
+		Protocol:        plugin.Protocol(c.Protocol),
+		ProtocolVersion: c.ProtocolVersion,
+		Addr:            addr,
+		Pid:             c.Pid,
+		Test:            c.Test,
+	}, nil
+}
+
+func (c Consumer) serveDebug() {
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer cancel()
+
+	reattachCh := make(chan *plugin.ReattachConfig)
+	closeCh := make(chan struct{})
+	config := c.serveConfig()
+	config.Test = &plugin.ServeTestConfig{
+		Context:          ctx,
+		ReattachConfigCh: reattachCh,
+		CloseCh:          closeCh,
+	}
+	go plugin.Serve(config)
+
+	var reattach *plugin.ReattachConfig
+	select {
+	case reattach = <-reattachCh:
+	case <-closeCh:
+		return
+	}
+	b, err := json.Marshal(map[string]ReattachConfig{c.name: newReattachConfig(reattach)})
+	if err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "failed to print reattach configuration: %v\n", err)
+		return
+	}
+	_, _ = fmt.Fprintf(os.Stdout, "Plugin %s is running in debug mode with pid %d.\n", c.name, reattach.Pid)
+	_, _ = fmt.Fprintf(os.Stdout, "To connect jpoet to it, set the following environment variable or pass its value to --reattach:\n\n")
+	_, _ = fmt.Fprintf(os.Stdout, "\t%s='%s'\n\n", ReattachEnv, b)
+	<-closeCh
+}
@@ -0,0 +1,130 @@
+package plugin
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+)
+
+const (
+	HandleKey       = "$handle"
+	HandlePluginKey = "$plugin"
+)
+
+type Handle struct {
+	Value any
+}
+
+func NewHandle(value any) *Handle {
+	return &Handle{Value: value}
+}
+
+type HandleReleaser interface {
+	Release(handles []string) error
+}
+
+func Release(invoker Invoker, handles []string) error {
+	if r, ok := invoker.(HandleReleaser); ok && len(handles) > 0 {
+		return r.Release(handles)
+	}
+	return nil
+}
+
+type generational interface {
+	Generation() uint64
+}
+
+func Generation(invoker Invoker) uint64 {
+	if g, ok := invoker.(generational); ok {
+		return g.Generation()
+	}
+	return 0
+}
+
+func HandleID(value any) (string, bool) {
+	obj, ok := value.(map[string]any)
+	if !ok {
+		return "", false
+	}
+	id, ok := obj[HandleKey].(string)
+	return id, ok
+}
+
+type handleStore struct {
+	prefix string
+	mu     sync.Mutex
+	nextID uint64
+	values map[string]any
+}
+
+func newHandleStore() *handleStore {
+	return &handleStore{
+		prefix: rand.Text(),
+		values: make(map[string]any),
+	}
+}
+
+func (s *handleStore) add(handle *Handle) map[string]any {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.nextID++
+	id := fmt.Sprintf("%s-%d", s.prefix, s.nextID)
+	s.values[id] = handle.Value
+	return map[string]any{HandleKey: id}
+}
+
+func (s *handleStore) resolve(args []any) ([]any, error) {
+	var resolved []any
+	for i, arg := range args {
+		id, ok := HandleID(arg)
+		if !ok {
+			continue
+		}
+		if resolved == nil {
+			resolved = append([]any{}, args...)
+		}
+		s.mu.Lock()
+		value, ok := s.values[id]
+		s.mu.Unlock()
+		if !ok {
+			return nil, fmt.Errorf("unknown handle %s, it may have been released", id)
+		}
+		resolved[i] = value
+	}
+	if resolved == nil {
+		return args, nil
+	}
+	return resolved, nil
+}
+
+func (s *handleStore) release(ids []string) error {
+	s.mu.Lock()
+	var closers []io.Closer
+	for _, id := range ids {
+		if c, ok := s.values[id].(io.Closer); ok {
+			closers = append(closers, c)
+		}
+		delete(s.values, id)
+	}
+	s.mu.Unlock()
+	var errs []error
+	for _, c := range closers {
+		err := c.Close()
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (s *handleStore) releaseAll() error {
+	s.mu.Lock()
+	ids := make([]string, 0, len(s.values))
+	for id := range s.values {
+		ids = append(ids, id)
+	}
+	s.mu.Unlock()
+	return s.release(ids)
+}
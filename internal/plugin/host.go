@@ -0,0 +1,216 @@
+package plugin
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/google/go-jsonnet/ast"
+	"github.com/hashicorp/go-plugin"
+	"github.com/marcbran/jpoet/internal/plugin/proto"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+type Host interface {
+	Evaluate(filename string, snippet string) (any, error)
+	Import(importedFrom string, importedPath string) (contents string, foundAt string, err error)
+}
+
+type HostFunction struct {
+	Name   string
+	Params ast.Identifiers
+	Func   func(host Host, args []any) (any, error)
+}
+
+type HostInvoker interface {
+	InvokeWithHost(host Host, funcName string, args []any) (any, error)
+}
+
+type HostBatchInvoker interface {
+	InvokeBatchWithHost(host Host, calls []Call) ([]Result, error)
+}
+
+func InvokeWithHost(invoker Invoker, host Host, funcName string, args []any) (any, error) {
+	if h, ok := invoker.(HostInvoker); ok && host != nil {
+		return h.InvokeWithHost(host, funcName, args)
+	}
+	return invoker.Invoke(funcName, args)
+}
+
+func InvokeBatchWithHost(invoker Invoker, host Host, calls []Call) ([]Result, error) {
+	if host == nil {
+		return InvokeBatch(invoker, calls)
+	}
+	if h, ok := invoker.(HostBatchInvoker); ok {
+		return h.InvokeBatchWithHost(host, calls)
+	}
+	if _, ok := invoker.(HostInvoker); !ok {
+		return InvokeBatch(invoker, calls)
+	}
+	results := make([]Result, len(calls))
+	for i, call := range calls {
+		value, err := InvokeWithHost(invoker, host, call.FuncName, call.Args)
+		results[i] = Result{Value: value, Err: err}
+	}
+	return results, nil
+}
+
+type hostRegistry struct {
+	proto.UnimplementedHostServer
+	broker *plugin.GRPCBroker
+
+	once     sync.Once
+	brokerID uint32
+
+	mu        sync.Mutex
+	nextScope uint64
+	hosts     map[uint64]Host
+}
+
+func newHostRegistry(broker *plugin.GRPCBroker) *hostRegistry {
+	return &hostRegistry{
+		broker: broker,
+		hosts:  make(map[uint64]Host),
+	}
+}
+
+func (r *hostRegistry) register(host Host) (uint32, uint64) {
+	r.once.Do(func() {
+		r.brokerID = r.broker.NextId()
+		go r.broker.AcceptAndServe(r.brokerID, func(opts []grpc.ServerOption) *grpc.Server {
+			s := grpc.NewServer(opts...)
+			proto.RegisterHostServer(s, r)
+			return s
+		})
+	})
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.nextScope++
+	r.hosts[r.nextScope] = host
+	return r.brokerID, r.nextScope
+}
+
+func (r *hostRegistry) unregister(scope uint64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.hosts, scope)
+}
+
+func (r *hostRegistry) host(scope uint64) (Host, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	host, ok := r.hosts[scope]
+	if !ok {
+		return nil, status.Errorf(codes.FailedPrecondition, "host scope %d is not active, the host can only be used during the invocation it was passed to", scope)
+	}
+	return host, nil
+}
+
+func (r *hostRegistry) Evaluate(ctx context.Context, request *proto.EvaluateRequest) (*proto.EvaluateResponse, error) {
+	host, err := r.host(request.Scope)
+	if err != nil {
+		return nil, err
+	}
+	value, err := host.Evaluate(request.Filename, request.Snippet)
+	if err != nil {
+		return nil, err
+	}
+	b, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	return &proto.EvaluateResponse{Value: b}, nil
+}
+
+func (r *hostRegistry) Import(ctx context.Context, request *proto.ImportRequest) (*proto.ImportResponse, error) {
+	host, err := r.host(request.Scope)
+	if err != nil {
+		return nil, err
+	}
+	contents, foundAt, err := host.Import(request.ImportedFrom, request.ImportedPath)
+	if err != nil {
+		return nil, err
+	}
+	return &proto.ImportResponse{Contents: contents, FoundAt: foundAt}, nil
+}
+
+type remoteHosts struct {
+	broker *plugin.GRPCBroker
+
+	mu    sync.Mutex
+	conns map[uint32]*grpc.ClientConn
+}
+
+func newRemoteHosts(broker *plugin.GRPCBroker) *remoteHosts {
+	return &remoteHosts{
+		broker: broker,
+		conns:  make(map[uint32]*grpc.ClientConn),
+	}
+}
+
+func (h *remoteHosts) host(brokerID uint32, scope uint64) (Host, error) {
+	if brokerID == 0 {
+		return nil, nil
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	conn, ok := h.conns[brokerID]
+	if !ok {
+		var err error
+		conn, err = h.broker.Dial(brokerID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to connect to host: %w", err)
+		}
+		h.conns[brokerID] = conn
+	}
+	return remoteHost{
+		client: proto.NewHostClient(conn),
+		scope:  scope,
+	}, nil
+}
+
+type remoteHost struct {
+	client proto.HostClient
+	scope  uint64
+}
+
+func (h remoteHost) Evaluate(filename string, snippet string) (any, error) {
+	resp, err := h.client.Evaluate(context.Background(), &proto.EvaluateRequest{
+		Scope:    h.scope,
+		Filename: filename,
+		Snippet:  snippet,
+	})
+	if err != nil {
+		return nil, hostError(err)
+	}
+	var value any
+	err = json.Unmarshal(resp.Value, &value)
+	if err != nil {
+		return nil, err
+	}
+	return value, nil
+}
+
+func (h remoteHost) Import(importedFrom string, importedPath string) (string, string, error) {
+	resp, err := h.client.Import(context.Background(), &proto.ImportRequest{
+		Scope:        h.scope,
+		ImportedFrom: importedFrom,
+		ImportedPath: importedPath,
+	})
+	if err != nil {
+		return "", "", hostError(err)
+	}
+	return resp.Contents, resp.FoundAt, nil
+}
+
+func hostError(err error) error {
+	s, ok := status.FromError(err)
+	if !ok || s.Code() == codes.Unavailable {
+		return err
+	}
+	return errors.New(s.Message())
+}
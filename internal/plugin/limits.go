@@ -0,0 +1,246 @@
+package plugin
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/go-plugin/runner"
+)
+
+type Limits struct {
+	// Memory is applied as RLIMIT_DATA rather than RLIMIT_AS, as Go programs reserve far more address space than they use.
+	Memory    uint64
+	CPUTime   time.Duration
+	OpenFiles uint64
+	Processes uint64
+	Cgroup    bool
+}
+
+func (l Limits) IsZero() bool {
+	return l == Limits{}
+}
+
+func (l *Limits) Set(key string, value string) error {
+	var err error
+	switch key {
+	case "memory":
+		l.Memory, err = parseSize(value)
+	case "cpuTime":
+		l.CPUTime, err = time.ParseDuration(value)
+	case "openFiles":
+		l.OpenFiles, err = strconv.ParseUint(value, 10, 64)
+	case "processes":
+		l.Processes, err = strconv.ParseUint(value, 10, 64)
+	case "cgroup":
+		l.Cgroup, err = strconv.ParseBool(value)
+	default:
+		return fmt.Errorf("unknown limit %q, expected one of memory, cpuTime, openFiles, processes or cgroup", key)
+	}
+	if err != nil {
+		return fmt.Errorf("invalid value %q for limit %s: %w", value, key, err)
+	}
+	return nil
+}
+
+func (l *Limits) UnmarshalJSON(b []byte) error {
+	return unmarshalValues(b, "limit", l.Set)
+}
+
+func unmarshalValues(b []byte, kind string, set func(key string, value string) error) error {
+	var values map[string]any
+	err := json.Unmarshal(b, &values)
+	if err != nil {
+		return err
+	}
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		var value string
+		switch v := values[key].(type) {
+		case string:
+			value = v
+		case float64:
+			value = strconv.FormatFloat(v, 'f', -1, 64)
+		case bool:
+			value = strconv.FormatBool(v)
+		default:
+			return fmt.Errorf("invalid value for %s %s: %v", kind, key, v)
+		}
+		err = set(key, value)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+var sizeSuffixes = []struct {
+	suffix string
+	factor uint64
+}{
+	{"KiB", 1 << 10},
+	{"MiB", 1 << 20},
+	{"GiB", 1 << 30},
+	{"K", 1 << 10},
+	{"M", 1 << 20},
+	{"G", 1 << 30},
+}
+
+func parseSize(s string) (uint64, error) {
+	for _, suffix := range sizeSuffixes {
+		number, ok := strings.CutSuffix(s, suffix.suffix)
+		if !ok {
+			continue
+		}
+		n, err := strconv.ParseUint(strings.TrimSpace(number), 10, 64)
+		if err != nil {
+			return 0, err
+		}
+		return n * suffix.factor, nil
+	}
+	return strconv.ParseUint(s, 10, 64)
+}
+
+type limitedProcess interface {
+	limitExceeded() string
+}
+
+var exhaustionMessages = map[string][]string{
+	"memory":    {"out of memory", "cannot allocate memory"},
+	"processes": {"failed to create new os thread", "resource temporarily unavailable"},
+	"openFiles": {"too many open files"},
+}
+
+type stderrWatcher struct {
+	mu   sync.Mutex
+	tail string
+	seen map[string]bool
+}
+
+func (w *stderrWatcher) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	text := w.tail + strings.ToLower(string(p))
+	for limit, messages := range exhaustionMessages {
+		for _, message := range messages {
+			if strings.Contains(text, message) {
+				if w.seen == nil {
+					w.seen = make(map[string]bool)
+				}
+				w.seen[limit] = true
+			}
+		}
+	}
+	if len(text) > 64 {
+		text = text[len(text)-64:]
+	}
+	w.tail = text
+	return len(p), nil
+}
+
+func (w *stderrWatcher) saw(limit string) bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.seen[limit]
+}
+
+type limitedRunner struct {
+	logger  hclog.Logger
+	path    string
+	cmd     *exec.Cmd
+	limiter *limiter
+	stdout  io.ReadCloser
+	stderr  io.ReadCloser
+}
+
+func limitedRunnerFunc(name string, path string, cmd *exec.Cmd, l *limiter) func(hclog.Logger, *exec.Cmd, string) (runner.Runner, error) {
+	return func(logger hclog.Logger, spec *exec.Cmd, _ string) (runner.Runner, error) {
+		cmd.Env = append(cmd.Env, spec.Env...)
+		cmd.Stdin = spec.Stdin
+		stdout, err := cmd.StdoutPipe()
+		if err != nil {
+			return nil, err
+		}
+		stderr, err := cmd.StderrPipe()
+		if err != nil {
+			return nil, err
+		}
+		return &limitedRunner{
+			logger:  logger.Named(name),
+			path:    path,
+			cmd:     cmd,
+			limiter: l,
+			stdout:  stdout,
+			stderr: struct {
+				io.Reader
+				io.Closer
+			}{io.TeeReader(stderr, l.stderrWriter()), stderr},
+		}, nil
+	}
+}
+
+func (r *limitedRunner) Start(_ context.Context) error {
+	return r.limiter.start(r.cmd)
+}
+
+func (r *limitedRunner) Wait(_ context.Context) error {
+	err := r.cmd.Wait()
+	r.limiter.exited(r.cmd.ProcessState)
+	return err
+}
+
+func (r *limitedRunner) Kill(_ context.Context) error {
+	if r.cmd.Process == nil {
+		return nil
+	}
+	err := r.cmd.Process.Kill()
+	if err != nil && !errors.Is(err, os.ErrProcessDone) {
+		return err
+	}
+	return nil
+}
+
+func (r *limitedRunner) Diagnose(_ context.Context) string {
+	return fmt.Sprintf("the plugin %s failed to start with its resource limits or failed the handshake", r.path)
+}
+
+func (r *limitedRunner) Stdout() io.ReadCloser {
+	return r.stdout
+}
+
+func (r *limitedRunner) Stderr() io.ReadCloser {
+	return r.stderr
+}
+
+func (r *limitedRunner) Name() string {
+	return r.path
+}
+
+func (r *limitedRunner) ID() string {
+	if r.cmd.Process == nil {
+		return ""
+	}
+	return strconv.Itoa(r.cmd.Process.Pid)
+}
+
+func (r *limitedRunner) PluginToHost(pluginNet, pluginAddr string) (string, string, error) {
+	return pluginNet, pluginAddr, nil
+}
+
+func (r *limitedRunner) HostToPlugin(hostNet, hostAddr string) (string, string, error) {
+	return hostNet, hostAddr, nil
+}
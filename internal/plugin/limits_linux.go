@@ -0,0 +1,295 @@
+package plugin
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/hashicorp/go-hclog"
+	"golang.org/x/sys/unix"
+)
+
+const (
+	cgroupRoot         = "/sys/fs/cgroup"
+	limitReasonTimeout = time.Second
+)
+
+type limiter struct {
+	name   string
+	limits Limits
+	logger hclog.Logger
+
+	cgroup string
+	stderr stderrWatcher
+	done   chan struct{}
+	state  *os.ProcessState
+	events map[string]uint64
+}
+
+func newLimiter(name string, limits Limits) (*limiter, error) {
+	return &limiter{
+		name:   name,
+		limits: limits,
+		logger: newLogger().Named(name),
+		done:   make(chan struct{}),
+	}, nil
+}
+
+func (l *limiter) start(cmd *exec.Cmd) error {
+	var cgroupFD *os.File
+	if l.limits.Cgroup && (l.limits.Memory > 0 || l.limits.Processes > 0) {
+		var err error
+		cgroupFD, err = l.createCgroup()
+		if err != nil {
+			l.logger.Warn("running plugin without cgroup, only rlimits apply", "error", err)
+		} else {
+			if cmd.SysProcAttr == nil {
+				cmd.SysProcAttr = &syscall.SysProcAttr{}
+			}
+			cmd.SysProcAttr.UseCgroupFD = true
+			cmd.SysProcAttr.CgroupFD = int(cgroupFD.Fd())
+		}
+	}
+	rlimits := l.rlimits()
+	trampolined := false
+	if len(rlimits) > 0 && trampolineEnabled.Load() {
+		err := throughTrampoline(cmd, func(spec *trampolineSpec) {
+			spec.Rlimits = rlimits
+		})
+		if err != nil {
+			l.logger.Debug("applying rlimits after the plugin has been started", "error", err)
+		}
+		trampolined = err == nil
+	}
+	err := cmd.Start()
+	if cgroupFD != nil {
+		_ = cgroupFD.Close()
+	}
+	if err != nil {
+		l.removeCgroup()
+		return err
+	}
+	if trampolined {
+		return nil
+	}
+	err = apply(cmd.Process.Pid, rlimits)
+	if err != nil {
+		_ = cmd.Process.Kill()
+		_ = cmd.Wait()
+		l.exited(cmd.ProcessState)
+		return fmt.Errorf("failed to apply resource limits to plugin %s: %w", l.name, err)
+	}
+	return nil
+}
+
+type rlimit struct {
+	Name     string `json:"name"`
+	Resource int    `json:"resource"`
+	Soft     uint64 `json:"soft"`
+	Hard     uint64 `json:"hard"`
+}
+
+func (l *limiter) rlimits() []rlimit {
+	var rlimits []rlimit
+	if l.limits.Memory > 0 {
+		rlimits = append(rlimits, rlimit{"memory", unix.RLIMIT_DATA, l.limits.Memory, l.limits.Memory})
+	}
+	if l.limits.CPUTime > 0 {
+		seconds := uint64(math.Ceil(l.limits.CPUTime.Seconds()))
+		rlimits = append(rlimits, rlimit{"CPU time", unix.RLIMIT_CPU, seconds, seconds + 1})
+	}
+	if l.limits.OpenFiles > 0 {
+		rlimits = append(rlimits, rlimit{"open files", unix.RLIMIT_NOFILE, l.limits.OpenFiles, l.limits.OpenFiles})
+	}
+	if l.limits.Processes > 0 {
+		rlimits = append(rlimits, rlimit{"processes", unix.RLIMIT_NPROC, l.limits.Processes, l.limits.Processes})
+	}
+	return rlimits
+}
+
+func apply(pid int, rlimits []rlimit) error {
+	for _, r := range rlimits {
+		err := unix.Prlimit(pid, r.Resource, &unix.Rlimit{Cur: r.Soft, Max: r.Hard}, nil)
+		if err != nil {
+			return fmt.Errorf("%s: %w", r.Name, err)
+		}
+	}
+	return nil
+}
+
+func (l *limiter) createCgroup() (*os.File, error) {
+	parent, err := ownCgroup()
+	if err != nil {
+		return nil, err
+	}
+	var controllers []string
+	if l.limits.Memory > 0 {
+		controllers = append(controllers, "memory")
+	}
+	if l.limits.Processes > 0 {
+		controllers = append(controllers, "pids")
+	}
+	err = enableControllers(parent, controllers)
+	if err != nil {
+		return nil, err
+	}
+	dir, err := os.MkdirTemp(parent, "jpoet-"+l.name+"-")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cgroup: %w", err)
+	}
+	l.cgroup = dir
+	if l.limits.Memory > 0 {
+		err = writeCgroupFile(dir, "memory.max", strconv.FormatUint(l.limits.Memory, 10))
+		if err != nil {
+			l.removeCgroup()
+			return nil, err
+		}
+		err = writeCgroupFile(dir, "memory.swap.max", "0")
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			l.removeCgroup()
+			return nil, err
+		}
+	}
+	if l.limits.Processes > 0 {
+		err = writeCgroupFile(dir, "pids.max", strconv.FormatUint(l.limits.Processes, 10))
+		if err != nil {
+			l.removeCgroup()
+			return nil, err
+		}
+	}
+	f, err := os.OpenFile(dir, unix.O_DIRECTORY|os.O_RDONLY, 0)
+	if err != nil {
+		l.removeCgroup()
+		return nil, err
+	}
+	return f, nil
+}
+
+func ownCgroup() (string, error) {
+	f, err := os.Open("/proc/self/cgroup")
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		path, ok := strings.CutPrefix(scanner.Text(), "0::")
+		if ok {
+			return filepath.Join(cgroupRoot, path), nil
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return "", err
+	}
+	return "", errors.New("cgroup v2 is not available")
+}
+
+func enableControllers(dir string, controllers []string) error {
+	b, err := os.ReadFile(filepath.Join(dir, "cgroup.subtree_control"))
+	if err != nil {
+		return fmt.Errorf("cgroup v2 is not available: %w", err)
+	}
+	enabled := strings.Fields(string(b))
+	for _, controller := range controllers {
+		if slices.Contains(enabled, controller) {
+			continue
+		}
+		err = writeCgroupFile(dir, "cgroup.subtree_control", "+"+controller)
+		if err != nil {
+			return fmt.Errorf("failed to enable the %s controller for the cgroup of jpoet: %w", controller, err)
+		}
+	}
+	return nil
+}
+
+func writeCgroupFile(dir string, name string, value string) error {
+	return os.WriteFile(filepath.Join(dir, name), []byte(value), 0)
+}
+
+func readCgroupEvents(dir string, name string, events map[string]uint64) {
+	b, err := os.ReadFile(filepath.Join(dir, name))
+	if err != nil {
+		return
+	}
+	for _, line := range strings.Split(string(b), "\n") {
+		key, value, ok := strings.Cut(line, " ")
+		if !ok {
+			continue
+		}
+		n, err := strconv.ParseUint(value, 10, 64)
+		if err != nil {
+			continue
+		}
+		events[name+":"+key] = n
+	}
+}
+
+func (l *limiter) removeCgroup() {
+	if l.cgroup == "" {
+		return
+	}
+	err := os.Remove(l.cgroup)
+	if err != nil {
+		l.logger.Debug("failed to remove cgroup", "cgroup", l.cgroup, "error", err)
+	}
+	l.cgroup = ""
+}
+
+func (l *limiter) exited(state *os.ProcessState) {
+	events := make(map[string]uint64)
+	if l.cgroup != "" {
+		readCgroupEvents(l.cgroup, "memory.events", events)
+		readCgroupEvents(l.cgroup, "pids.events", events)
+		l.removeCgroup()
+	}
+	l.state = state
+	l.events = events
+	close(l.done)
+}
+
+func (l *limiter) stderrWriter() io.Writer {
+	return &l.stderr
+}
+
+func (l *limiter) limitExceeded() string {
+	select {
+	case <-l.done:
+	case <-time.After(limitReasonTimeout):
+		return ""
+	}
+	if l.limits.Memory > 0 && l.events["memory.events:oom_kill"] > 0 {
+		return fmt.Sprintf("memory limit of %d bytes", l.limits.Memory)
+	}
+	if l.limits.Processes > 0 && l.events["pids.events:max"] > 0 {
+		return fmt.Sprintf("process limit of %d", l.limits.Processes)
+	}
+	if l.limits.CPUTime > 0 && l.state != nil {
+		if status, ok := l.state.Sys().(syscall.WaitStatus); ok && status.Signaled() {
+			signal := status.Signal()
+			used := l.state.UserTime() + l.state.SystemTime()
+			if signal == syscall.SIGXCPU || (signal == syscall.SIGKILL && used >= l.limits.CPUTime) {
+				return fmt.Sprintf("CPU time limit of %s", l.limits.CPUTime)
+			}
+		}
+	}
+	if l.limits.Memory > 0 && l.stderr.saw("memory") {
+		return fmt.Sprintf("memory limit of %d bytes", l.limits.Memory)
+	}
+	if l.limits.Processes > 0 && l.stderr.saw("processes") {
+		return fmt.Sprintf("process limit of %d", l.limits.Processes)
+	}
+	if l.limits.OpenFiles > 0 && l.stderr.saw("openFiles") {
+		return fmt.Sprintf("open files limit of %d", l.limits.OpenFiles)
+	}
+	return ""
+}
@@ -0,0 +1,68 @@
+package plugin
+
+import (
+	"encoding/json"
+	"os/exec"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestStdioInvoker_CPUTimeLimit(t *testing.T) {
+	invoker := newConfiguredStdioTestInvoker(t, ProcessConfig{
+		Limits: Limits{CPUTime: time.Second},
+	})
+
+	_, err := invoker.Invoke("spin", []any{})
+	if err == nil || !strings.Contains(err.Error(), "plugin test was stopped because it exceeded its CPU time limit of 1s") {
+		t.Errorf("expected CPU time limit error, got: %v", err)
+	}
+}
+
+func TestStdioInvoker_OpenFilesLimit(t *testing.T) {
+	invoker := newConfiguredStdioTestInvoker(t, ProcessConfig{
+		Limits: Limits{OpenFiles: 32},
+	})
+
+	_, err := invoker.Invoke("open", []any{64.0})
+	if err == nil || !strings.Contains(err.Error(), "plugin test was stopped because it exceeded its open files limit of 32") {
+		t.Errorf("expected open files limit error, got: %v", err)
+	}
+
+	res, err := invoker.Invoke("open", []any{4.0})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if res != true {
+		t.Errorf("expected restarted plugin to open files, got %v", res)
+	}
+}
+
+func TestThroughTrampoline(t *testing.T) {
+	cmd := exec.Command("/plugins/test")
+	cmd.Env = []string{"PATH=/bin"}
+	err := throughTrampoline(cmd, func(spec *trampolineSpec) {
+		spec.Sandbox = &sandboxSpec{Read: []string{"/data"}}
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	err = throughTrampoline(cmd, func(spec *trampolineSpec) {
+		spec.Rlimits = []rlimit{{Name: "open files", Resource: 7, Soft: 32, Hard: 32}}
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(cmd.Args) != 2 || cmd.Args[1] != trampolineArg || len(cmd.Env) != 2 {
+		t.Fatalf("expected command to start the trampoline once, got %v with %v", cmd.Args, cmd.Env)
+	}
+	var spec trampolineSpec
+	err = json.Unmarshal([]byte(strings.TrimPrefix(cmd.Env[1], trampolineEnv+"=")), &spec)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if spec.Path != "/plugins/test" || spec.Sandbox == nil || len(spec.Rlimits) != 1 {
+		t.Errorf("unexpected spec: %+v", spec)
+	}
+}
@@ -0,0 +1,33 @@
+//go:build !linux
+
+package plugin
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+)
+
+type limiter struct{}
+
+func newLimiter(name string, limits Limits) (*limiter, error) {
+	if !limits.IsZero() {
+		return nil, fmt.Errorf("resource limits of plugin %s are only supported on Linux", name)
+	}
+	return &limiter{}, nil
+}
+
+func (l *limiter) start(cmd *exec.Cmd) error {
+	return cmd.Start()
+}
+
+func (l *limiter) exited(_ *os.ProcessState) {}
+
+func (l *limiter) stderrWriter() io.Writer {
+	return io.Discard
+}
+
+func (l *limiter) limitExceeded() string {
+	return ""
+}
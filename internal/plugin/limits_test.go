@@ -0,0 +1,58 @@
+package plugin
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLimits_Set(t *testing.T) {
+	var limits Limits
+	for key, value := range map[string]string{
+		"memory":    "512MiB",
+		"cpuTime":   "1m30s",
+		"openFiles": "256",
+		"processes": "64",
+		"cgroup":    "true",
+	} {
+		err := limits.Set(key, value)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	expected := Limits{
+		Memory:    512 << 20,
+		CPUTime:   90 * time.Second,
+		OpenFiles: 256,
+		Processes: 64,
+		Cgroup:    true,
+	}
+	if limits != expected {
+		t.Errorf("expected %+v, got %+v", expected, limits)
+	}
+}
+
+func TestLimits_SetInvalid(t *testing.T) {
+	var limits Limits
+	err := limits.Set("memory", "lots")
+	if err == nil || !strings.Contains(err.Error(), `invalid value "lots" for limit memory`) {
+		t.Errorf("expected invalid value error, got: %v", err)
+	}
+	err = limits.Set("disk", "1G")
+	if err == nil || !strings.Contains(err.Error(), `unknown limit "disk"`) {
+		t.Errorf("expected unknown limit error, got: %v", err)
+	}
+}
+
+func TestLimits_UnmarshalJSON(t *testing.T) {
+	var limits Limits
+	err := json.Unmarshal([]byte(`{"memory": 1048576, "cpuTime": "10s", "cgroup": true}`), &limits)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := Limits{Memory: 1 << 20, CPUTime: 10 * time.Second, Cgroup: true}
+	if limits != expected {
+		t.Errorf("expected %+v, got %+v", expected, limits)
+	}
+}
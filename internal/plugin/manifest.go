@@ -0,0 +1,80 @@
+package plugin
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+const ManifestFile = "plugin.json"
+
+type Manifest struct {
+	Name       string `json:"name"`
+	NativeName string `json:"nativeName"`
+	Version    string `json:"version"`
+	Source     string `json:"source"`
+	SHA256     string `json:"sha256"`
+	Protocol   string `json:"protocol,omitempty"`
+}
+
+func ReadManifest(pluginDir string) (*Manifest, error) {
+	path := filepath.Join(pluginDir, ManifestFile)
+	b, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var m Manifest
+	err = json.Unmarshal(b, &m)
+	if err != nil {
+		return nil, fmt.Errorf("invalid plugin manifest %s: %w", path, err)
+	}
+	if m.Name == "" || m.NativeName == "" || m.SHA256 == "" {
+		return nil, fmt.Errorf("invalid plugin manifest %s: name, nativeName and sha256 are required", path)
+	}
+	return &m, nil
+}
+
+func (m Manifest) Write(pluginDir string) error {
+	b, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(pluginDir, ManifestFile), append(b, '\n'), 0644)
+}
+
+func FileSHA256(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	hash := sha256.New()
+	_, err = io.Copy(hash, f)
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+func verifyChecksum(name string, path string, expected string) error {
+	if expected == "" {
+		return nil
+	}
+	actual, err := FileSHA256(path)
+	if err != nil {
+		return fmt.Errorf("failed to verify plugin %s: %w", name, err)
+	}
+	if actual != expected {
+		return fmt.Errorf("plugin %s has been modified since it was installed: %s has sha256 %s, but its manifest records %s", name, path, actual, expected)
+	}
+	return nil
+}
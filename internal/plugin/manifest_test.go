@@ -0,0 +1,75 @@
+package plugin
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestManifest_ReadWrite(t *testing.T) {
+	dir := t.TempDir()
+	m, err := ReadManifest(dir)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if m != nil {
+		t.Fatalf("expected no manifest, got %+v", m)
+	}
+
+	expected := Manifest{
+		Name:       "jsonnet-plugin-test",
+		NativeName: "test",
+		Version:    "v1.0.0",
+		Source:     "github.com/marcbran/jsonnet-plugin-test",
+		SHA256:     "abc",
+	}
+	err = expected.Write(dir)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	m, err = ReadManifest(dir)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if *m != expected {
+		t.Errorf("expected %+v, got %+v", expected, *m)
+	}
+}
+
+func TestManifest_ReadIncomplete(t *testing.T) {
+	dir := t.TempDir()
+	err := os.WriteFile(filepath.Join(dir, ManifestFile), []byte(`{"name": "jsonnet-plugin-test"}`), 0o644)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	_, err = ReadManifest(dir)
+	if err == nil || !strings.Contains(err.Error(), "name, nativeName and sha256 are required") {
+		t.Errorf("expected incomplete manifest error, got: %v", err)
+	}
+}
+
+func TestVerifyChecksum(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "jsonnet-plugin-test")
+	err := os.WriteFile(path, []byte("plugin"), 0o755)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	sum, err := FileSHA256(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	err = verifyChecksum("test", path, sum)
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	err = os.WriteFile(path, []byte("tampered"), 0o755)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	err = verifyChecksum("test", path, sum)
+	if err == nil || !strings.Contains(err.Error(), "plugin test has been modified since it was installed") {
+		t.Errorf("expected checksum error, got: %v", err)
+	}
+}
@@ -25,6 +25,8 @@ type InvokeRequest struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	FuncName      string                 `protobuf:"bytes,1,opt,name=funcName,proto3" json:"funcName,omitempty"`
 	Args          []byte                 `protobuf:"bytes,2,opt,name=args,proto3" json:"args,omitempty"`
+	HostId        uint32                 `protobuf:"varint,3,opt,name=hostId,proto3" json:"hostId,omitempty"`
+	HostScope     uint64                 `protobuf:"varint,4,opt,name=hostScope,proto3" json:"hostScope,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
@@ -73,6 +75,20 @@ func (x *InvokeRequest) GetArgs() []byte {
 	return nil
 }
 
+func (x *InvokeRequest) GetHostId() uint32 {
+	if x != nil {
+		return x.HostId
+	}
+	return 0
+}
+
+func (x *InvokeRequest) GetHostScope() uint64 {
+	if x != nil {
+		return x.HostScope
+	}
+	return 0
+}
+
 type InvokeResponse struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	Value         []byte                 `protobuf:"bytes,1,opt,name=value,proto3" json:"value,omitempty"`
@@ -117,18 +133,908 @@ func (x *InvokeResponse) GetValue() []byte {
 	return nil
 }
 
-var File_model_proto protoreflect.FileDescriptor
+type InvokeChunk struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	FuncName      string                 `protobuf:"bytes,1,opt,name=funcName,proto3" json:"funcName,omitempty"`
+	Data          []byte                 `protobuf:"bytes,2,opt,name=data,proto3" json:"data,omitempty"`
+	Error         string                 `protobuf:"bytes,3,opt,name=error,proto3" json:"error,omitempty"`
+	HostId        uint32                 `protobuf:"varint,4,opt,name=hostId,proto3" json:"hostId,omitempty"`
+	HostScope     uint64                 `protobuf:"varint,5,opt,name=hostScope,proto3" json:"hostScope,omitempty"`
+	Retryable     bool                   `protobuf:"varint,6,opt,name=retryable,proto3" json:"retryable,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
 
-const file_model_proto_rawDesc = "" +
-	"\n" +
-	"\vmodel.proto\x12\x06plugin\"?\n" +
-	"\rInvokeRequest\x12\x1a\n" +
-	"\bfuncName\x18\x01 \x01(\tR\bfuncName\x12\x12\n" +
-	"\x04args\x18\x02 \x01(\fR\x04args\"&\n" +
-	"\x0eInvokeResponse\x12\x14\n" +
-	"\x05value\x18\x01 \x01(\fR\x05value2B\n" +
+func (x *InvokeChunk) Reset() {
+	*x = InvokeChunk{}
+	mi := &file_model_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *InvokeChunk) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*InvokeChunk) ProtoMessage() {}
+
+func (x *InvokeChunk) ProtoReflect() protoreflect.Message {
+	mi := &file_model_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use InvokeChunk.ProtoReflect.Descriptor instead.
+func (*InvokeChunk) Descriptor() ([]byte, []int) {
+	return file_model_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *InvokeChunk) GetFuncName() string {
+	if x != nil {
+		return x.FuncName
+	}
+	return ""
+}
+
+func (x *InvokeChunk) GetData() []byte {
+	if x != nil {
+		return x.Data
+	}
+	return nil
+}
+
+func (x *InvokeChunk) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+func (x *InvokeChunk) GetHostId() uint32 {
+	if x != nil {
+		return x.HostId
+	}
+	return 0
+}
+
+func (x *InvokeChunk) GetHostScope() uint64 {
+	if x != nil {
+		return x.HostScope
+	}
+	return 0
+}
+
+func (x *InvokeChunk) GetRetryable() bool {
+	if x != nil {
+		return x.Retryable
+	}
+	return false
+}
+
+type InvokeBatchRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Calls         []*InvokeRequest       `protobuf:"bytes,1,rep,name=calls,proto3" json:"calls,omitempty"`
+	HostId        uint32                 `protobuf:"varint,2,opt,name=hostId,proto3" json:"hostId,omitempty"`
+	HostScope     uint64                 `protobuf:"varint,3,opt,name=hostScope,proto3" json:"hostScope,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *InvokeBatchRequest) Reset() {
+	*x = InvokeBatchRequest{}
+	mi := &file_model_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *InvokeBatchRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*InvokeBatchRequest) ProtoMessage() {}
+
+func (x *InvokeBatchRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_model_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use InvokeBatchRequest.ProtoReflect.Descriptor instead.
+func (*InvokeBatchRequest) Descriptor() ([]byte, []int) {
+	return file_model_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *InvokeBatchRequest) GetCalls() []*InvokeRequest {
+	if x != nil {
+		return x.Calls
+	}
+	return nil
+}
+
+func (x *InvokeBatchRequest) GetHostId() uint32 {
+	if x != nil {
+		return x.HostId
+	}
+	return 0
+}
+
+func (x *InvokeBatchRequest) GetHostScope() uint64 {
+	if x != nil {
+		return x.HostScope
+	}
+	return 0
+}
+
+type InvokeResult struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Value         []byte                 `protobuf:"bytes,1,opt,name=value,proto3" json:"value,omitempty"`
+	Error         string                 `protobuf:"bytes,2,opt,name=error,proto3" json:"error,omitempty"`
+	Retryable     bool                   `protobuf:"varint,3,opt,name=retryable,proto3" json:"retryable,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *InvokeResult) Reset() {
+	*x = InvokeResult{}
+	mi := &file_model_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *InvokeResult) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*InvokeResult) ProtoMessage() {}
+
+func (x *InvokeResult) ProtoReflect() protoreflect.Message {
+	mi := &file_model_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use InvokeResult.ProtoReflect.Descriptor instead.
+func (*InvokeResult) Descriptor() ([]byte, []int) {
+	return file_model_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *InvokeResult) GetValue() []byte {
+	if x != nil {
+		return x.Value
+	}
+	return nil
+}
+
+func (x *InvokeResult) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+func (x *InvokeResult) GetRetryable() bool {
+	if x != nil {
+		return x.Retryable
+	}
+	return false
+}
+
+type InvokeBatchResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Results       []*InvokeResult        `protobuf:"bytes,1,rep,name=results,proto3" json:"results,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *InvokeBatchResponse) Reset() {
+	*x = InvokeBatchResponse{}
+	mi := &file_model_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *InvokeBatchResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*InvokeBatchResponse) ProtoMessage() {}
+
+func (x *InvokeBatchResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_model_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use InvokeBatchResponse.ProtoReflect.Descriptor instead.
+func (*InvokeBatchResponse) Descriptor() ([]byte, []int) {
+	return file_model_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *InvokeBatchResponse) GetResults() []*InvokeResult {
+	if x != nil {
+		return x.Results
+	}
+	return nil
+}
+
+type ConfigureRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Settings      []byte                 `protobuf:"bytes,1,opt,name=settings,proto3" json:"settings,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ConfigureRequest) Reset() {
+	*x = ConfigureRequest{}
+	mi := &file_model_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ConfigureRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ConfigureRequest) ProtoMessage() {}
+
+func (x *ConfigureRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_model_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ConfigureRequest.ProtoReflect.Descriptor instead.
+func (*ConfigureRequest) Descriptor() ([]byte, []int) {
+	return file_model_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *ConfigureRequest) GetSettings() []byte {
+	if x != nil {
+		return x.Settings
+	}
+	return nil
+}
+
+type ConfigureResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ConfigureResponse) Reset() {
+	*x = ConfigureResponse{}
+	mi := &file_model_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ConfigureResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ConfigureResponse) ProtoMessage() {}
+
+func (x *ConfigureResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_model_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ConfigureResponse.ProtoReflect.Descriptor instead.
+func (*ConfigureResponse) Descriptor() ([]byte, []int) {
+	return file_model_proto_rawDescGZIP(), []int{7}
+}
+
+type DescribeRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DescribeRequest) Reset() {
+	*x = DescribeRequest{}
+	mi := &file_model_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DescribeRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DescribeRequest) ProtoMessage() {}
+
+func (x *DescribeRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_model_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DescribeRequest.ProtoReflect.Descriptor instead.
+func (*DescribeRequest) Descriptor() ([]byte, []int) {
+	return file_model_proto_rawDescGZIP(), []int{8}
+}
+
+type CacheHint struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Pure          bool                   `protobuf:"varint,1,opt,name=pure,proto3" json:"pure,omitempty"`
+	TtlMillis     int64                  `protobuf:"varint,2,opt,name=ttlMillis,proto3" json:"ttlMillis,omitempty"`
+	Never         bool                   `protobuf:"varint,3,opt,name=never,proto3" json:"never,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CacheHint) Reset() {
+	*x = CacheHint{}
+	mi := &file_model_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CacheHint) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CacheHint) ProtoMessage() {}
+
+func (x *CacheHint) ProtoReflect() protoreflect.Message {
+	mi := &file_model_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CacheHint.ProtoReflect.Descriptor instead.
+func (*CacheHint) Descriptor() ([]byte, []int) {
+	return file_model_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *CacheHint) GetPure() bool {
+	if x != nil {
+		return x.Pure
+	}
+	return false
+}
+
+func (x *CacheHint) GetTtlMillis() int64 {
+	if x != nil {
+		return x.TtlMillis
+	}
+	return 0
+}
+
+func (x *CacheHint) GetNever() bool {
+	if x != nil {
+		return x.Never
+	}
+	return false
+}
+
+type FunctionInfo struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Name          string                 `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	Params        []string               `protobuf:"bytes,2,rep,name=params,proto3" json:"params,omitempty"`
+	Cache         *CacheHint             `protobuf:"bytes,3,opt,name=cache,proto3" json:"cache,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *FunctionInfo) Reset() {
+	*x = FunctionInfo{}
+	mi := &file_model_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *FunctionInfo) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*FunctionInfo) ProtoMessage() {}
+
+func (x *FunctionInfo) ProtoReflect() protoreflect.Message {
+	mi := &file_model_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use FunctionInfo.ProtoReflect.Descriptor instead.
+func (*FunctionInfo) Descriptor() ([]byte, []int) {
+	return file_model_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *FunctionInfo) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *FunctionInfo) GetParams() []string {
+	if x != nil {
+		return x.Params
+	}
+	return nil
+}
+
+func (x *FunctionInfo) GetCache() *CacheHint {
+	if x != nil {
+		return x.Cache
+	}
+	return nil
+}
+
+type DescribeResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Name          string                 `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	Functions     []*FunctionInfo        `protobuf:"bytes,2,rep,name=functions,proto3" json:"functions,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DescribeResponse) Reset() {
+	*x = DescribeResponse{}
+	mi := &file_model_proto_msgTypes[11]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DescribeResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DescribeResponse) ProtoMessage() {}
+
+func (x *DescribeResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_model_proto_msgTypes[11]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DescribeResponse.ProtoReflect.Descriptor instead.
+func (*DescribeResponse) Descriptor() ([]byte, []int) {
+	return file_model_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *DescribeResponse) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *DescribeResponse) GetFunctions() []*FunctionInfo {
+	if x != nil {
+		return x.Functions
+	}
+	return nil
+}
+
+type ReleaseRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Handles       []string               `protobuf:"bytes,1,rep,name=handles,proto3" json:"handles,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ReleaseRequest) Reset() {
+	*x = ReleaseRequest{}
+	mi := &file_model_proto_msgTypes[12]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ReleaseRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ReleaseRequest) ProtoMessage() {}
+
+func (x *ReleaseRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_model_proto_msgTypes[12]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ReleaseRequest.ProtoReflect.Descriptor instead.
+func (*ReleaseRequest) Descriptor() ([]byte, []int) {
+	return file_model_proto_rawDescGZIP(), []int{12}
+}
+
+func (x *ReleaseRequest) GetHandles() []string {
+	if x != nil {
+		return x.Handles
+	}
+	return nil
+}
+
+type ReleaseResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ReleaseResponse) Reset() {
+	*x = ReleaseResponse{}
+	mi := &file_model_proto_msgTypes[13]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ReleaseResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ReleaseResponse) ProtoMessage() {}
+
+func (x *ReleaseResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_model_proto_msgTypes[13]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ReleaseResponse.ProtoReflect.Descriptor instead.
+func (*ReleaseResponse) Descriptor() ([]byte, []int) {
+	return file_model_proto_rawDescGZIP(), []int{13}
+}
+
+type EvaluateRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Scope         uint64                 `protobuf:"varint,1,opt,name=scope,proto3" json:"scope,omitempty"`
+	Filename      string                 `protobuf:"bytes,2,opt,name=filename,proto3" json:"filename,omitempty"`
+	Snippet       string                 `protobuf:"bytes,3,opt,name=snippet,proto3" json:"snippet,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *EvaluateRequest) Reset() {
+	*x = EvaluateRequest{}
+	mi := &file_model_proto_msgTypes[14]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *EvaluateRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*EvaluateRequest) ProtoMessage() {}
+
+func (x *EvaluateRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_model_proto_msgTypes[14]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use EvaluateRequest.ProtoReflect.Descriptor instead.
+func (*EvaluateRequest) Descriptor() ([]byte, []int) {
+	return file_model_proto_rawDescGZIP(), []int{14}
+}
+
+func (x *EvaluateRequest) GetScope() uint64 {
+	if x != nil {
+		return x.Scope
+	}
+	return 0
+}
+
+func (x *EvaluateRequest) GetFilename() string {
+	if x != nil {
+		return x.Filename
+	}
+	return ""
+}
+
+func (x *EvaluateRequest) GetSnippet() string {
+	if x != nil {
+		return x.Snippet
+	}
+	return ""
+}
+
+type EvaluateResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Value         []byte                 `protobuf:"bytes,1,opt,name=value,proto3" json:"value,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *EvaluateResponse) Reset() {
+	*x = EvaluateResponse{}
+	mi := &file_model_proto_msgTypes[15]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *EvaluateResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*EvaluateResponse) ProtoMessage() {}
+
+func (x *EvaluateResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_model_proto_msgTypes[15]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use EvaluateResponse.ProtoReflect.Descriptor instead.
+func (*EvaluateResponse) Descriptor() ([]byte, []int) {
+	return file_model_proto_rawDescGZIP(), []int{15}
+}
+
+func (x *EvaluateResponse) GetValue() []byte {
+	if x != nil {
+		return x.Value
+	}
+	return nil
+}
+
+type ImportRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Scope         uint64                 `protobuf:"varint,1,opt,name=scope,proto3" json:"scope,omitempty"`
+	ImportedFrom  string                 `protobuf:"bytes,2,opt,name=importedFrom,proto3" json:"importedFrom,omitempty"`
+	ImportedPath  string                 `protobuf:"bytes,3,opt,name=importedPath,proto3" json:"importedPath,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ImportRequest) Reset() {
+	*x = ImportRequest{}
+	mi := &file_model_proto_msgTypes[16]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ImportRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ImportRequest) ProtoMessage() {}
+
+func (x *ImportRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_model_proto_msgTypes[16]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ImportRequest.ProtoReflect.Descriptor instead.
+func (*ImportRequest) Descriptor() ([]byte, []int) {
+	return file_model_proto_rawDescGZIP(), []int{16}
+}
+
+func (x *ImportRequest) GetScope() uint64 {
+	if x != nil {
+		return x.Scope
+	}
+	return 0
+}
+
+func (x *ImportRequest) GetImportedFrom() string {
+	if x != nil {
+		return x.ImportedFrom
+	}
+	return ""
+}
+
+func (x *ImportRequest) GetImportedPath() string {
+	if x != nil {
+		return x.ImportedPath
+	}
+	return ""
+}
+
+type ImportResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Contents      string                 `protobuf:"bytes,1,opt,name=contents,proto3" json:"contents,omitempty"`
+	FoundAt       string                 `protobuf:"bytes,2,opt,name=foundAt,proto3" json:"foundAt,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ImportResponse) Reset() {
+	*x = ImportResponse{}
+	mi := &file_model_proto_msgTypes[17]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ImportResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ImportResponse) ProtoMessage() {}
+
+func (x *ImportResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_model_proto_msgTypes[17]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ImportResponse.ProtoReflect.Descriptor instead.
+func (*ImportResponse) Descriptor() ([]byte, []int) {
+	return file_model_proto_rawDescGZIP(), []int{17}
+}
+
+func (x *ImportResponse) GetContents() string {
+	if x != nil {
+		return x.Contents
+	}
+	return ""
+}
+
+func (x *ImportResponse) GetFoundAt() string {
+	if x != nil {
+		return x.FoundAt
+	}
+	return ""
+}
+
+var File_model_proto protoreflect.FileDescriptor
+
+const file_model_proto_rawDesc = "" +
+	"\n" +
+	"\vmodel.proto\x12\x06plugin\"u\n" +
+	"\rInvokeRequest\x12\x1a\n" +
+	"\bfuncName\x18\x01 \x01(\tR\bfuncName\x12\x12\n" +
+	"\x04args\x18\x02 \x01(\fR\x04args\x12\x16\n" +
+	"\x06hostId\x18\x03 \x01(\rR\x06hostId\x12\x1c\n" +
+	"\thostScope\x18\x04 \x01(\x04R\thostScope\"&\n" +
+	"\x0eInvokeResponse\x12\x14\n" +
+	"\x05value\x18\x01 \x01(\fR\x05value\"\xa7\x01\n" +
+	"\vInvokeChunk\x12\x1a\n" +
+	"\bfuncName\x18\x01 \x01(\tR\bfuncName\x12\x12\n" +
+	"\x04data\x18\x02 \x01(\fR\x04data\x12\x14\n" +
+	"\x05error\x18\x03 \x01(\tR\x05error\x12\x16\n" +
+	"\x06hostId\x18\x04 \x01(\rR\x06hostId\x12\x1c\n" +
+	"\thostScope\x18\x05 \x01(\x04R\thostScope\x12\x1c\n" +
+	"\tretryable\x18\x06 \x01(\bR\tretryable\"w\n" +
+	"\x12InvokeBatchRequest\x12+\n" +
+	"\x05calls\x18\x01 \x03(\v2\x15.plugin.InvokeRequestR\x05calls\x12\x16\n" +
+	"\x06hostId\x18\x02 \x01(\rR\x06hostId\x12\x1c\n" +
+	"\thostScope\x18\x03 \x01(\x04R\thostScope\"X\n" +
+	"\fInvokeResult\x12\x14\n" +
+	"\x05value\x18\x01 \x01(\fR\x05value\x12\x14\n" +
+	"\x05error\x18\x02 \x01(\tR\x05error\x12\x1c\n" +
+	"\tretryable\x18\x03 \x01(\bR\tretryable\"E\n" +
+	"\x13InvokeBatchResponse\x12.\n" +
+	"\aresults\x18\x01 \x03(\v2\x14.plugin.InvokeResultR\aresults\".\n" +
+	"\x10ConfigureRequest\x12\x1a\n" +
+	"\bsettings\x18\x01 \x01(\fR\bsettings\"\x13\n" +
+	"\x11ConfigureResponse\"\x11\n" +
+	"\x0fDescribeRequest\"S\n" +
+	"\tCacheHint\x12\x12\n" +
+	"\x04pure\x18\x01 \x01(\bR\x04pure\x12\x1c\n" +
+	"\tttlMillis\x18\x02 \x01(\x03R\tttlMillis\x12\x14\n" +
+	"\x05never\x18\x03 \x01(\bR\x05never\"c\n" +
+	"\fFunctionInfo\x12\x12\n" +
+	"\x04name\x18\x01 \x01(\tR\x04name\x12\x16\n" +
+	"\x06params\x18\x02 \x03(\tR\x06params\x12'\n" +
+	"\x05cache\x18\x03 \x01(\v2\x11.plugin.CacheHintR\x05cache\"Z\n" +
+	"\x10DescribeResponse\x12\x12\n" +
+	"\x04name\x18\x01 \x01(\tR\x04name\x122\n" +
+	"\tfunctions\x18\x02 \x03(\v2\x14.plugin.FunctionInfoR\tfunctions\"*\n" +
+	"\x0eReleaseRequest\x12\x18\n" +
+	"\ahandles\x18\x01 \x03(\tR\ahandles\"\x11\n" +
+	"\x0fReleaseResponse\"]\n" +
+	"\x0fEvaluateRequest\x12\x14\n" +
+	"\x05scope\x18\x01 \x01(\x04R\x05scope\x12\x1a\n" +
+	"\bfilename\x18\x02 \x01(\tR\bfilename\x12\x18\n" +
+	"\asnippet\x18\x03 \x01(\tR\asnippet\"(\n" +
+	"\x10EvaluateResponse\x12\x14\n" +
+	"\x05value\x18\x01 \x01(\fR\x05value\"m\n" +
+	"\rImportRequest\x12\x14\n" +
+	"\x05scope\x18\x01 \x01(\x04R\x05scope\x12\"\n" +
+	"\fimportedFrom\x18\x02 \x01(\tR\fimportedFrom\x12\"\n" +
+	"\fimportedPath\x18\x03 \x01(\tR\fimportedPath\"F\n" +
+	"\x0eImportResponse\x12\x1a\n" +
+	"\bcontents\x18\x01 \x01(\tR\bcontents\x12\x18\n" +
+	"\afoundAt\x18\x02 \x01(\tR\afoundAt2\x85\x03\n" +
 	"\aInvoker\x127\n" +
-	"\x06Invoke\x12\x15.plugin.InvokeRequest\x1a\x16.plugin.InvokeResponseB\tZ\a./protob\x06proto3"
+	"\x06Invoke\x12\x15.plugin.InvokeRequest\x1a\x16.plugin.InvokeResponse\x12@\n" +
+	"\tConfigure\x12\x18.plugin.ConfigureRequest\x1a\x19.plugin.ConfigureResponse\x12=\n" +
+	"\bDescribe\x12\x17.plugin.DescribeRequest\x1a\x18.plugin.DescribeResponse\x12F\n" +
+	"\vInvokeBatch\x12\x1a.plugin.InvokeBatchRequest\x1a\x1b.plugin.InvokeBatchResponse\x12<\n" +
+	"\fInvokeStream\x12\x13.plugin.InvokeChunk\x1a\x13.plugin.InvokeChunk(\x010\x01\x12:\n" +
+	"\aRelease\x12\x16.plugin.ReleaseRequest\x1a\x17.plugin.ReleaseResponse2~\n" +
+	"\x04Host\x12=\n" +
+	"\bEvaluate\x12\x17.plugin.EvaluateRequest\x1a\x18.plugin.EvaluateResponse\x127\n" +
+	"\x06Import\x12\x15.plugin.ImportRequest\x1a\x16.plugin.ImportResponseB\tZ\a./protob\x06proto3"
 
 var (
 	file_model_proto_rawDescOnce sync.Once
@@ -142,19 +1048,53 @@ func file_model_proto_rawDescGZIP() []byte {
 	return file_model_proto_rawDescData
 }
 
-var file_model_proto_msgTypes = make([]protoimpl.MessageInfo, 2)
+var file_model_proto_msgTypes = make([]protoimpl.MessageInfo, 18)
 var file_model_proto_goTypes = []any{
-	(*InvokeRequest)(nil),  // 0: plugin.InvokeRequest
-	(*InvokeResponse)(nil), // 1: plugin.InvokeResponse
+	(*InvokeRequest)(nil),       // 0: plugin.InvokeRequest
+	(*InvokeResponse)(nil),      // 1: plugin.InvokeResponse
+	(*InvokeChunk)(nil),         // 2: plugin.InvokeChunk
+	(*InvokeBatchRequest)(nil),  // 3: plugin.InvokeBatchRequest
+	(*InvokeResult)(nil),        // 4: plugin.InvokeResult
+	(*InvokeBatchResponse)(nil), // 5: plugin.InvokeBatchResponse
+	(*ConfigureRequest)(nil),    // 6: plugin.ConfigureRequest
+	(*ConfigureResponse)(nil),   // 7: plugin.ConfigureResponse
+	(*DescribeRequest)(nil),     // 8: plugin.DescribeRequest
+	(*CacheHint)(nil),           // 9: plugin.CacheHint
+	(*FunctionInfo)(nil),        // 10: plugin.FunctionInfo
+	(*DescribeResponse)(nil),    // 11: plugin.DescribeResponse
+	(*ReleaseRequest)(nil),      // 12: plugin.ReleaseRequest
+	(*ReleaseResponse)(nil),     // 13: plugin.ReleaseResponse
+	(*EvaluateRequest)(nil),     // 14: plugin.EvaluateRequest
+	(*EvaluateResponse)(nil),    // 15: plugin.EvaluateResponse
+	(*ImportRequest)(nil),       // 16: plugin.ImportRequest
+	(*ImportResponse)(nil),      // 17: plugin.ImportResponse
 }
 var file_model_proto_depIdxs = []int32{
-	0, // 0: plugin.Invoker.Invoke:input_type -> plugin.InvokeRequest
-	1, // 1: plugin.Invoker.Invoke:output_type -> plugin.InvokeResponse
-	1, // [1:2] is the sub-list for method output_type
-	0, // [0:1] is the sub-list for method input_type
-	0, // [0:0] is the sub-list for extension type_name
-	0, // [0:0] is the sub-list for extension extendee
-	0, // [0:0] is the sub-list for field type_name
+	0,  // 0: plugin.InvokeBatchRequest.calls:type_name -> plugin.InvokeRequest
+	4,  // 1: plugin.InvokeBatchResponse.results:type_name -> plugin.InvokeResult
+	9,  // 2: plugin.FunctionInfo.cache:type_name -> plugin.CacheHint
+	10, // 3: plugin.DescribeResponse.functions:type_name -> plugin.FunctionInfo
+	0,  // 4: plugin.Invoker.Invoke:input_type -> plugin.InvokeRequest
+	6,  // 5: plugin.Invoker.Configure:input_type -> plugin.ConfigureRequest
+	8,  // 6: plugin.Invoker.Describe:input_type -> plugin.DescribeRequest
+	3,  // 7: plugin.Invoker.InvokeBatch:input_type -> plugin.InvokeBatchRequest
+	2,  // 8: plugin.Invoker.InvokeStream:input_type -> plugin.InvokeChunk
+	12, // 9: plugin.Invoker.Release:input_type -> plugin.ReleaseRequest
+	14, // 10: plugin.Host.Evaluate:input_type -> plugin.EvaluateRequest
+	16, // 11: plugin.Host.Import:input_type -> plugin.ImportRequest
+	1,  // 12: plugin.Invoker.Invoke:output_type -> plugin.InvokeResponse
+	7,  // 13: plugin.Invoker.Configure:output_type -> plugin.ConfigureResponse
+	11, // 14: plugin.Invoker.Describe:output_type -> plugin.DescribeResponse
+	5,  // 15: plugin.Invoker.InvokeBatch:output_type -> plugin.InvokeBatchResponse
+	2,  // 16: plugin.Invoker.InvokeStream:output_type -> plugin.InvokeChunk
+	13, // 17: plugin.Invoker.Release:output_type -> plugin.ReleaseResponse
+	15, // 18: plugin.Host.Evaluate:output_type -> plugin.EvaluateResponse
+	17, // 19: plugin.Host.Import:output_type -> plugin.ImportResponse
+	12, // [12:20] is the sub-list for method output_type
+	4,  // [4:12] is the sub-list for method input_type
+	4,  // [4:4] is the sub-list for extension type_name
+	4,  // [4:4] is the sub-list for extension extendee
+	0,  // [0:4] is the sub-list for field type_name
 }
 
 func init() { file_model_proto_init() }
@@ -168,9 +1108,9 @@ func file_model_proto_init() {
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: unsafe.Slice(unsafe.StringData(file_model_proto_rawDesc), len(file_model_proto_rawDesc)),
 			NumEnums:      0,
-			NumMessages:   2,
+			NumMessages:   18,
 			NumExtensions: 0,
-			NumServices:   1,
+			NumServices:   2,
 		},
 		GoTypes:           file_model_proto_goTypes,
 		DependencyIndexes: file_model_proto_depIdxs,
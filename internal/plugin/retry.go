@@ -0,0 +1,137 @@
+package plugin
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"strconv"
+	"time"
+
+	"github.com/marcbran/jpoet/internal/plugin/proto"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+type RetryableError struct {
+	Err error
+}
+
+func (e RetryableError) Error() string {
+	return e.Err.Error()
+}
+
+func (e RetryableError) Unwrap() error {
+	return e.Err
+}
+
+func Retryable(err error) error {
+	if err == nil {
+		return nil
+	}
+	return RetryableError{Err: err}
+}
+
+func IsRetryable(err error) bool {
+	var r RetryableError
+	return errors.As(err, &r)
+}
+
+func remoteError(message string, retryable bool) error {
+	err := errors.New(message)
+	if retryable {
+		return Retryable(err)
+	}
+	return err
+}
+
+func retryableStatus(err error) error {
+	if !IsRetryable(err) {
+		return err
+	}
+	s, detailErr := status.New(codes.Unknown, err.Error()).WithDetails(&proto.InvokeResult{Error: err.Error(), Retryable: true})
+	if detailErr != nil {
+		return err
+	}
+	return s.Err()
+}
+
+func statusError(err error) error {
+	for _, detail := range status.Convert(err).Details() {
+		if r, ok := detail.(*proto.InvokeResult); ok {
+			return remoteError(r.Error, r.Retryable)
+		}
+	}
+	return err
+}
+
+type Timeouts struct {
+	Default   time.Duration
+	Functions map[string]time.Duration
+}
+
+func (t Timeouts) IsZero() bool {
+	return t.Default == 0 && len(t.Functions) == 0
+}
+
+func (t Timeouts) For(funcName string) time.Duration {
+	if timeout, ok := t.Functions[funcName]; ok {
+		return timeout
+	}
+	return t.Default
+}
+
+func (t *Timeouts) Set(funcName string, value string) error {
+	timeout, err := time.ParseDuration(value)
+	if err != nil {
+		return fmt.Errorf("invalid timeout %q for %s: %w", value, funcName, err)
+	}
+	if funcName == "default" {
+		t.Default = timeout
+		return nil
+	}
+	t.Functions = maps.Clone(t.Functions)
+	if t.Functions == nil {
+		t.Functions = make(map[string]time.Duration)
+	}
+	t.Functions[funcName] = timeout
+	return nil
+}
+
+func (t *Timeouts) UnmarshalJSON(b []byte) error {
+	var value string
+	if json.Unmarshal(b, &value) == nil {
+		return t.Set("default", value)
+	}
+	return unmarshalValues(b, "timeout", t.Set)
+}
+
+type RetryPolicy struct {
+	Attempts   int
+	Backoff    time.Duration
+	MaxBackoff time.Duration
+}
+
+const DefaultBackoff = 100 * time.Millisecond
+
+func (p *RetryPolicy) Set(key string, value string) error {
+	var err error
+	switch key {
+	case "attempts":
+		p.Attempts, err = strconv.Atoi(value)
+	case "backoff":
+		p.Backoff, err = time.ParseDuration(value)
+	case "maxBackoff":
+		p.MaxBackoff, err = time.ParseDuration(value)
+	default:
+		return fmt.Errorf("unknown retry setting %q, expected one of attempts, backoff or maxBackoff", key)
+	}
+	if err != nil {
+		return fmt.Errorf("invalid value %q for retry setting %s: %w", value, key, err)
+	}
+	return nil
+}
+
+func (p *RetryPolicy) UnmarshalJSON(b []byte) error {
+	return unmarshalValues(b, "retry setting", p.Set)
+}
@@ -0,0 +1,116 @@
+package plugin
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRetryable(t *testing.T) {
+	err := Retryable(errors.New("busy"))
+	if !IsRetryable(err) {
+		t.Errorf("expected error to be retryable")
+	}
+	if !IsRetryable(errors.Join(errors.New("wrapped"), err)) {
+		t.Errorf("expected wrapped error to be retryable")
+	}
+	if IsRetryable(errors.New("busy")) {
+		t.Errorf("expected error not to be retryable")
+	}
+	if Retryable(nil) != nil {
+		t.Errorf("expected nil")
+	}
+}
+
+func TestTimeouts_Set(t *testing.T) {
+	var timeouts Timeouts
+	for funcName, value := range map[string]string{
+		"default": "5s",
+		"fetch":   "1m",
+	} {
+		err := timeouts.Set(funcName, value)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if timeouts.For("fetch") != time.Minute {
+		t.Errorf("expected 1m, got %s", timeouts.For("fetch"))
+	}
+	if timeouts.For("upper") != 5*time.Second {
+		t.Errorf("expected 5s, got %s", timeouts.For("upper"))
+	}
+	err := timeouts.Set("fetch", "soon")
+	if err == nil || !strings.Contains(err.Error(), `invalid timeout "soon" for fetch`) {
+		t.Errorf("expected invalid timeout error, got: %v", err)
+	}
+}
+
+func TestTimeouts_SetCopies(t *testing.T) {
+	var timeouts Timeouts
+	_ = timeouts.Set("fetch", "1s")
+	other := timeouts
+	_ = other.Set("fetch", "2s")
+	if timeouts.For("fetch") != time.Second {
+		t.Errorf("expected original timeouts to be unchanged, got %s", timeouts.For("fetch"))
+	}
+}
+
+func TestTimeouts_UnmarshalJSON(t *testing.T) {
+	var timeouts Timeouts
+	err := json.Unmarshal([]byte(`"10s"`), &timeouts)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if timeouts.Default != 10*time.Second || len(timeouts.Functions) != 0 {
+		t.Errorf("unexpected timeouts: %+v", timeouts)
+	}
+
+	timeouts = Timeouts{}
+	err = json.Unmarshal([]byte(`{"default": "10s", "fetch": "1m"}`), &timeouts)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if timeouts.Default != 10*time.Second || timeouts.For("fetch") != time.Minute {
+		t.Errorf("unexpected timeouts: %+v", timeouts)
+	}
+}
+
+func TestRetryPolicy_Set(t *testing.T) {
+	var policy RetryPolicy
+	for key, value := range map[string]string{
+		"attempts":   "3",
+		"backoff":    "50ms",
+		"maxBackoff": "1s",
+	} {
+		err := policy.Set(key, value)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	expected := RetryPolicy{Attempts: 3, Backoff: 50 * time.Millisecond, MaxBackoff: time.Second}
+	if policy != expected {
+		t.Errorf("expected %+v, got %+v", expected, policy)
+	}
+	err := policy.Set("jitter", "1")
+	if err == nil || !strings.Contains(err.Error(), `unknown retry setting "jitter"`) {
+		t.Errorf("expected unknown setting error, got: %v", err)
+	}
+	err = policy.Set("attempts", "many")
+	if err == nil || !strings.Contains(err.Error(), `invalid value "many" for retry setting attempts`) {
+		t.Errorf("expected invalid value error, got: %v", err)
+	}
+}
+
+func TestRetryPolicy_UnmarshalJSON(t *testing.T) {
+	var policy RetryPolicy
+	err := json.Unmarshal([]byte(`{"attempts": 5, "backoff": "10ms"}`), &policy)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := RetryPolicy{Attempts: 5, Backoff: 10 * time.Millisecond}
+	if policy != expected {
+		t.Errorf("expected %+v, got %+v", expected, policy)
+	}
+}
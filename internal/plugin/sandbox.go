@@ -0,0 +1,23 @@
+package plugin
+
+import (
+	"path/filepath"
+)
+
+type Sandbox struct {
+	Read    []string `json:"read"`
+	Write   []string `json:"write"`
+	Network bool     `json:"network"`
+}
+
+func (s *Sandbox) paths(paths []string) []string {
+	abs := make([]string, 0, len(paths))
+	for _, path := range paths {
+		p, err := filepath.Abs(path)
+		if err != nil {
+			p = path
+		}
+		abs = append(abs, p)
+	}
+	return abs
+}
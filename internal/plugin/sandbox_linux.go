@@ -0,0 +1,163 @@
+package plugin
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"sync"
+	"syscall"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+type sandboxSpec struct {
+	Read  []string `json:"read"`
+	Write []string `json:"write"`
+}
+
+const (
+	landlockReadAccess = unix.LANDLOCK_ACCESS_FS_EXECUTE | unix.LANDLOCK_ACCESS_FS_READ_FILE | unix.LANDLOCK_ACCESS_FS_READ_DIR
+	landlockFileAccess = unix.LANDLOCK_ACCESS_FS_EXECUTE | unix.LANDLOCK_ACCESS_FS_WRITE_FILE | unix.LANDLOCK_ACCESS_FS_READ_FILE |
+		unix.LANDLOCK_ACCESS_FS_TRUNCATE | unix.LANDLOCK_ACCESS_FS_IOCTL_DEV
+)
+
+var systemPaths = []string{"/bin", "/etc", "/lib", "/lib32", "/lib64", "/sbin", "/usr", "/dev/random", "/dev/urandom", "/proc/self"}
+
+var landlockABI = sync.OnceValue(func() int {
+	abi, _, errno := unix.Syscall(unix.SYS_LANDLOCK_CREATE_RULESET, 0, 0, unix.LANDLOCK_CREATE_RULESET_VERSION)
+	if errno != 0 {
+		return 0
+	}
+	return int(abi)
+})
+
+func landlockAccess(abi int) uint64 {
+	access := uint64(unix.LANDLOCK_ACCESS_FS_MAKE_SYM<<1 - 1)
+	if abi >= 2 {
+		access |= unix.LANDLOCK_ACCESS_FS_REFER
+	}
+	if abi >= 3 {
+		access |= unix.LANDLOCK_ACCESS_FS_TRUNCATE
+	}
+	if abi >= 5 {
+		access |= unix.LANDLOCK_ACCESS_FS_IOCTL_DEV
+	}
+	return access
+}
+
+func (s *Sandbox) confine(name string, cmd *exec.Cmd) error {
+	if !trampolineEnabled.Load() {
+		return fmt.Errorf("plugin %s cannot be sandboxed, because its host does not call jpoet.Trampoline", name)
+	}
+	if !s.Network {
+		if userNamespaces() {
+			if cmd.SysProcAttr == nil {
+				cmd.SysProcAttr = &syscall.SysProcAttr{}
+			}
+			cmd.SysProcAttr.Cloneflags |= syscall.CLONE_NEWUSER | syscall.CLONE_NEWNET
+			cmd.SysProcAttr.UidMappings = []syscall.SysProcIDMap{{ContainerID: os.Getuid(), HostID: os.Getuid(), Size: 1}}
+			cmd.SysProcAttr.GidMappings = []syscall.SysProcIDMap{{ContainerID: os.Getgid(), HostID: os.Getgid(), Size: 1}}
+		} else {
+			return fmt.Errorf("plugin %s cannot be denied network access, because the kernel does not support unprivileged user namespaces", name)
+		}
+	}
+	if landlockABI() == 0 {
+		return fmt.Errorf("plugin %s cannot be sandboxed, because the kernel does not support Landlock", name)
+	}
+	err := throughTrampoline(cmd, func(spec *trampolineSpec) {
+		spec.Sandbox = &sandboxSpec{Read: s.paths(s.Read), Write: s.paths(s.Write)}
+	})
+	if err != nil {
+		return fmt.Errorf("plugin %s cannot be sandboxed, because jpoet cannot start itself: %w", name, err)
+	}
+	return nil
+}
+
+var userNamespaces = sync.OnceValue(func() bool {
+	self, err := os.Executable()
+	if err != nil {
+		return false
+	}
+	cmd := exec.Command(self, trampolineArg)
+	cmd.Env = []string{fmt.Sprintf("%s=%s", trampolineEnv, trampolineProbe)}
+	cmd.SysProcAttr = &syscall.SysProcAttr{
+		Cloneflags:  syscall.CLONE_NEWUSER | syscall.CLONE_NEWNET,
+		UidMappings: []syscall.SysProcIDMap{{ContainerID: os.Getuid(), HostID: os.Getuid(), Size: 1}},
+		GidMappings: []syscall.SysProcIDMap{{ContainerID: os.Getgid(), HostID: os.Getgid(), Size: 1}},
+	}
+	return cmd.Run() == nil
+})
+
+func restrictSelf(path string, spec sandboxSpec) error {
+	access := landlockAccess(landlockABI())
+	attr := unix.LandlockRulesetAttr{Access_fs: access}
+	fd, _, errno := unix.Syscall(unix.SYS_LANDLOCK_CREATE_RULESET, uintptr(unsafe.Pointer(&attr)), unsafe.Sizeof(attr), 0)
+	if errno != 0 {
+		return fmt.Errorf("failed to create Landlock ruleset: %w", errno)
+	}
+	ruleset := int(fd)
+
+	rules := map[string]uint64{
+		path:        unix.LANDLOCK_ACCESS_FS_EXECUTE | unix.LANDLOCK_ACCESS_FS_READ_FILE,
+		"/dev/null": unix.LANDLOCK_ACCESS_FS_READ_FILE | unix.LANDLOCK_ACCESS_FS_WRITE_FILE,
+	}
+	for _, path := range systemPaths {
+		rules[path] |= landlockReadAccess
+	}
+	for _, path := range spec.Read {
+		rules[path] |= landlockReadAccess
+	}
+	for _, path := range spec.Write {
+		rules[path] |= access
+	}
+	socketDir, ok := os.LookupEnv("PLUGIN_UNIX_SOCKET_DIR")
+	if ok {
+		rules[socketDir] |= access
+	}
+	for path, pathAccess := range rules {
+		err := addLandlockRule(ruleset, path, pathAccess&access)
+		if err != nil {
+			return err
+		}
+	}
+
+	err := unix.Prctl(unix.PR_SET_NO_NEW_PRIVS, 1, 0, 0, 0)
+	if err != nil {
+		return fmt.Errorf("failed to set no_new_privs: %w", err)
+	}
+	_, _, errno = unix.Syscall(unix.SYS_LANDLOCK_RESTRICT_SELF, uintptr(ruleset), 0, 0)
+	if errno != 0 {
+		return fmt.Errorf("failed to enforce Landlock ruleset: %w", errno)
+	}
+	_ = unix.Close(ruleset)
+	return nil
+}
+
+func addLandlockRule(ruleset int, path string, access uint64) error {
+	fd, err := unix.Open(path, unix.O_PATH|unix.O_CLOEXEC, 0)
+	if err != nil {
+		if err == unix.ENOENT {
+			return nil
+		}
+		return fmt.Errorf("failed to open %s: %w", path, err)
+	}
+	defer unix.Close(fd)
+	var stat unix.Stat_t
+	err = unix.Fstat(fd, &stat)
+	if err != nil {
+		return fmt.Errorf("failed to stat %s: %w", path, err)
+	}
+	if stat.Mode&unix.S_IFMT != unix.S_IFDIR {
+		access &= landlockFileAccess
+	}
+	attr := unix.LandlockPathBeneathAttr{
+		Allowed_access: access,
+		Parent_fd:      int32(fd),
+	}
+	_, _, errno := unix.Syscall6(unix.SYS_LANDLOCK_ADD_RULE, uintptr(ruleset), unix.LANDLOCK_RULE_PATH_BENEATH, uintptr(unsafe.Pointer(&attr)), 0, 0, 0)
+	if errno != 0 {
+		return fmt.Errorf("failed to allow access to %s: %w", path, errno)
+	}
+	return nil
+}
@@ -0,0 +1,112 @@
+package plugin
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestStdioInvoker_SandboxFiles(t *testing.T) {
+	if landlockABI() == 0 {
+		t.Skip("kernel does not support Landlock")
+	}
+	allowed := filepath.Join(t.TempDir(), "allowed.txt")
+	denied := filepath.Join(t.TempDir(), "denied.txt")
+	for _, path := range []string{allowed, denied} {
+		err := os.WriteFile(path, []byte("content"), 0o600)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	invoker := newConfiguredStdioTestInvoker(t, ProcessConfig{
+		Sandbox: &Sandbox{Read: []string{filepath.Dir(allowed)}, Network: true},
+	})
+
+	res, err := invoker.Invoke("read", []any{allowed})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if res != "content" {
+		t.Errorf("expected content of allowed file, got %v", res)
+	}
+
+	_, err = invoker.Invoke("read", []any{denied})
+	if err == nil || !strings.Contains(err.Error(), "permission denied") {
+		t.Errorf("expected permission error, got: %v", err)
+	}
+}
+
+func TestStdioInvoker_SandboxNetwork(t *testing.T) {
+	if !userNamespaces() || landlockABI() == 0 {
+		t.Skip("kernel does not support unprivileged user namespaces and Landlock")
+	}
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer listener.Close()
+
+	invoker := newConfiguredStdioTestInvoker(t, ProcessConfig{
+		Sandbox: &Sandbox{},
+	})
+	_, err = invoker.Invoke("dial", []any{listener.Addr().String()})
+	if err == nil {
+		t.Errorf("expected network to be denied")
+	}
+
+	invoker = newConfiguredStdioTestInvoker(t, ProcessConfig{
+		Sandbox: &Sandbox{Network: true},
+	})
+	res, err := invoker.Invoke("dial", []any{listener.Addr().String()})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if res != true {
+		t.Errorf("expected network to be allowed, got %v", res)
+	}
+}
+
+func TestClientInvoker_SandboxFiles(t *testing.T) {
+	if landlockABI() == 0 {
+		t.Skip("kernel does not support Landlock")
+	}
+	allowed := filepath.Join(t.TempDir(), "allowed.txt")
+	denied := filepath.Join(t.TempDir(), "denied.txt")
+	for _, path := range []string{allowed, denied} {
+		err := os.WriteFile(path, []byte("content"), 0o600)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	invoker := newConfiguredGRPCProcessTestInvoker(t, ProcessConfig{
+		Sandbox: &Sandbox{Read: []string{filepath.Dir(allowed)}, Network: true},
+	})
+
+	res, err := invoker.Invoke("read", []any{allowed})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if res != "content" {
+		t.Errorf("expected content of allowed file, got %v", res)
+	}
+
+	_, err = invoker.Invoke("read", []any{denied})
+	if err == nil || !strings.Contains(err.Error(), "permission denied") {
+		t.Errorf("expected permission error, got: %v", err)
+	}
+}
+
+func TestStdioInvoker_SandboxWithoutTrampoline(t *testing.T) {
+	trampolineEnabled.Store(false)
+	defer trampolineEnabled.Store(true)
+
+	invoker := newConfiguredStdioTestInvoker(t, ProcessConfig{
+		Sandbox: &Sandbox{Network: true},
+	})
+	_, err := invoker.Invoke("upper", []any{"hello"})
+	if err == nil || !strings.Contains(err.Error(), "plugin test cannot be sandboxed, because its host does not call jpoet.Trampoline") {
+		t.Errorf("expected sandbox error, got: %v", err)
+	}
+}
@@ -0,0 +1,12 @@
+//go:build !linux
+
+package plugin
+
+import (
+	"fmt"
+	"os/exec"
+)
+
+func (s *Sandbox) confine(name string, _ *exec.Cmd) error {
+	return fmt.Errorf("plugin %s cannot be sandboxed, because sandboxing is only supported on Linux", name)
+}
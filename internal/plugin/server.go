@@ -4,30 +4,145 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"os"
 	"sort"
 	"strings"
 
 	"github.com/google/go-jsonnet"
+	"github.com/google/go-jsonnet/ast"
 	"github.com/hashicorp/go-plugin"
 	"github.com/marcbran/jpoet/internal/plugin/proto"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 func (c Consumer) Serve() {
-	plugin.Serve(&plugin.ServeConfig{
+	if os.Getenv(DebugEnv) != "" {
+		c.serveDebug()
+		return
+	}
+	plugin.Serve(c.serveConfig())
+}
+
+func (c Consumer) serveConfig() *plugin.ServeConfig {
+	return &plugin.ServeConfig{
 		HandshakeConfig: handshakeConfig,
-		Plugins: map[string]plugin.Plugin{
-			"invoker": &grpcPlugin{
-				Impl: c.invoker,
-			},
-		},
-		GRPCServer: plugin.DefaultGRPCServer,
+		VersionedPlugins: versionedPlugins(&grpcPlugin{
+			Name:       c.name,
+			Impl:       c.invoker,
+			Configure:  c.configure,
+			CacheHints: c.cacheHints,
+		}),
+		GRPCServer: grpcServer,
 		Logger:     newLogger(),
-	})
+	}
 }
 
 type grpcServerInvoker struct {
 	proto.UnimplementedInvokerServer
-	impl Invoker
+	name       string
+	impl       Invoker
+	configure  ConfigureFunc
+	cacheHints map[string]CacheHint
+	hosts      *remoteHosts
+}
+
+func (s grpcServerInvoker) Describe(
+	ctx context.Context,
+	request *proto.DescribeRequest,
+) (*proto.DescribeResponse, error) {
+	resp := &proto.DescribeResponse{
+		Name: s.name,
+	}
+	d, ok := s.impl.(describer)
+	if !ok {
+		return resp, nil
+	}
+	for _, f := range d.Functions() {
+		hint := s.cacheHints[f.Name]
+		resp.Functions = append(resp.Functions, &proto.FunctionInfo{
+			Name:   f.Name,
+			Params: f.Params,
+			Cache: &proto.CacheHint{
+				Pure:      hint.Pure,
+				TtlMillis: hint.TTL.Milliseconds(),
+				Never:     hint.Never,
+			},
+		})
+	}
+	return resp, nil
+}
+
+func (s grpcServerInvoker) InvokeBatch(
+	ctx context.Context,
+	request *proto.InvokeBatchRequest,
+) (*proto.InvokeBatchResponse, error) {
+	calls := make([]Call, len(request.Calls))
+	for i, call := range request.Calls {
+		var args []any
+		err := json.Unmarshal(call.Args, &args)
+		if err != nil {
+			return nil, status.Errorf(codes.InvalidArgument, "invalid arguments of call %d: %v", i, err)
+		}
+		calls[i] = Call{FuncName: call.FuncName, Args: args}
+	}
+	host, err := s.hosts.host(request.HostId, request.HostScope)
+	if err != nil {
+		return nil, err
+	}
+	results, err := InvokeBatchWithHost(s.impl, host, calls)
+	if err != nil {
+		return nil, err
+	}
+	resp := &proto.InvokeBatchResponse{
+		Results: make([]*proto.InvokeResult, len(results)),
+	}
+	for i, result := range results {
+		if result.Err != nil {
+			resp.Results[i] = &proto.InvokeResult{Error: result.Err.Error(), Retryable: IsRetryable(result.Err)}
+			continue
+		}
+		b, err := json.Marshal(result.Value)
+		if err != nil {
+			resp.Results[i] = &proto.InvokeResult{Error: err.Error()}
+			continue
+		}
+		resp.Results[i] = &proto.InvokeResult{Value: b}
+	}
+	return resp, nil
+}
+
+func (s grpcServerInvoker) Release(
+	ctx context.Context,
+	request *proto.ReleaseRequest,
+) (*proto.ReleaseResponse, error) {
+	err := Release(s.impl, request.Handles)
+	if err != nil {
+		return nil, err
+	}
+	return &proto.ReleaseResponse{}, nil
+}
+
+func (s grpcServerInvoker) Configure(
+	ctx context.Context,
+	request *proto.ConfigureRequest,
+) (*proto.ConfigureResponse, error) {
+	var settings map[string]any
+	err := json.Unmarshal(request.Settings, &settings)
+	if err != nil {
+		return nil, err
+	}
+	if s.configure == nil {
+		if len(settings) > 0 {
+			return nil, status.Error(codes.InvalidArgument, "plugin does not accept settings")
+		}
+		return &proto.ConfigureResponse{}, nil
+	}
+	err = s.configure(settings)
+	if err != nil {
+		return nil, err
+	}
+	return &proto.ConfigureResponse{}, nil
 }
 
 func (s grpcServerInvoker) Invoke(
@@ -39,10 +154,14 @@ func (s grpcServerInvoker) Invoke(
 	if err != nil {
 		return nil, err
 	}
-	resp, err := s.impl.Invoke(request.FuncName, args)
+	host, err := s.hosts.host(request.HostId, request.HostScope)
 	if err != nil {
 		return nil, err
 	}
+	resp, err := InvokeWithHost(s.impl, host, request.FuncName, args)
+	if err != nil {
+		return nil, retryableStatus(err)
+	}
 	b, err := json.Marshal(resp)
 	if err != nil {
 		return nil, err
@@ -55,32 +174,99 @@ func (s grpcServerInvoker) Invoke(
 type localInvoker struct {
 	functionNames string
 	functions     map[string]jsonnet.NativeFunction
+	hostFunctions map[string]HostFunction
+	handles       *handleStore
 }
 
 func NewLocalInvoker(
 	functions []jsonnet.NativeFunction,
-) Invoker {
+) InvokeCloser {
+	return NewLocalHostInvoker(functions, nil)
+}
+
+func NewLocalHostInvoker(
+	functions []jsonnet.NativeFunction,
+	hostFunctions []HostFunction,
+) InvokeCloser {
 	functionMap := make(map[string]jsonnet.NativeFunction)
+	hostFunctionMap := make(map[string]HostFunction)
 	var functionNames []string
 	for _, f := range functions {
 		functionNames = append(functionNames, f.Name)
 		functionMap[f.Name] = f
 	}
+	for _, f := range hostFunctions {
+		functionNames = append(functionNames, f.Name)
+		hostFunctionMap[f.Name] = f
+	}
 	sort.Strings(functionNames)
 	return &localInvoker{
 		functionNames: strings.Join(functionNames, ", "),
 		functions:     functionMap,
+		hostFunctions: hostFunctionMap,
+		handles:       newHandleStore(),
+	}
+}
+
+func (i localInvoker) Functions() []FunctionInfo {
+	var infos []FunctionInfo
+	for _, f := range i.functions {
+		infos = append(infos, functionInfo(f.Name, f.Params))
+	}
+	for _, f := range i.hostFunctions {
+		infos = append(infos, functionInfo(f.Name, f.Params))
+	}
+	sort.Slice(infos, func(a, b int) bool {
+		return infos[a].Name < infos[b].Name
+	})
+	return infos
+}
+
+func functionInfo(name string, params ast.Identifiers) FunctionInfo {
+	info := FunctionInfo{Name: name}
+	for _, p := range params {
+		info.Params = append(info.Params, string(p))
 	}
+	return info
 }
 
 func (i localInvoker) Invoke(funcName string, args []any) (any, error) {
-	f, ok := i.functions[funcName]
-	if !ok {
-		return "", fmt.Errorf("no such function: %s, available functions: %s", funcName, i.functionNames)
+	return i.InvokeWithHost(nil, funcName, args)
+}
+
+func (i localInvoker) InvokeWithHost(host Host, funcName string, args []any) (any, error) {
+	args, err := i.handles.resolve(args)
+	if err != nil {
+		return "", err
 	}
-	res, err := f.Func(args)
+	res, err := i.call(host, funcName, args)
 	if err != nil {
 		return "", err
 	}
+	if handle, ok := res.(*Handle); ok {
+		return i.handles.add(handle), nil
+	}
 	return res, nil
 }
+
+func (i localInvoker) call(host Host, funcName string, args []any) (any, error) {
+	if hf, ok := i.hostFunctions[funcName]; ok {
+		if host == nil {
+			return "", fmt.Errorf("function %s needs access to the host, but it was invoked without one", funcName)
+		}
+		return hf.Func(host, args)
+	}
+	f, ok := i.functions[funcName]
+	if !ok {
+		return "", fmt.Errorf("no such function: %s, available functions: %s", funcName, i.functionNames)
+	}
+	return f.Func(args)
+}
+
+func (i localInvoker) Release(handles []string) error {
+	return i.handles.release(handles)
+}
+
+func (i localInvoker) Close() error {
+	return i.handles.releaseAll()
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"io"
+	"time"
 
 	"github.com/google/go-jsonnet"
 	"github.com/google/go-jsonnet/ast"
@@ -18,6 +19,37 @@ var handshakeConfig = plugin.HandshakeConfig{
 	MagicCookieValue: "9af0e0b1-a9c4-47ed-a8c2-fc740428f447",
 }
 
+const (
+	minProtocolVersion = 1
+	maxProtocolVersion = 6
+)
+
+func versionedPlugins(impl *grpcPlugin) map[int]plugin.PluginSet {
+	sets := make(map[int]plugin.PluginSet)
+	for version := minProtocolVersion; version <= maxProtocolVersion; version++ {
+		sets[version] = plugin.PluginSet{
+			"invoker": impl,
+		}
+	}
+	return sets
+}
+
+type FunctionInfo struct {
+	Name   string
+	Params []string
+	Cache  CacheHint
+}
+
+type CacheHint struct {
+	Pure  bool
+	TTL   time.Duration
+	Never bool
+}
+
+type describer interface {
+	Functions() []FunctionInfo
+}
+
 type Invoker interface {
 	Invoke(funcName string, args []any) (any, error)
 }
@@ -27,9 +59,49 @@ type InvokeCloser interface {
 	io.Closer
 }
 
+type Call struct {
+	FuncName string
+	Args     []any
+}
+
+type Result struct {
+	Value any
+	Err   error
+}
+
+type BatchInvoker interface {
+	InvokeBatch(calls []Call) ([]Result, error)
+}
+
+func InvokeBatch(invoker Invoker, calls []Call) ([]Result, error) {
+	if b, ok := invoker.(BatchInvoker); ok {
+		return b.InvokeBatch(calls)
+	}
+	results := make([]Result, len(calls))
+	for i, call := range calls {
+		value, err := invoker.Invoke(call.FuncName, call.Args)
+		results[i] = Result{Value: value, Err: err}
+	}
+	return results, nil
+}
+
+type Aborter interface {
+	Abort()
+}
+
+func Abort(invoker Invoker) {
+	if a, ok := invoker.(Aborter); ok {
+		a.Abort()
+	}
+}
+
+type ConfigureFunc func(settings map[string]any) error
+
 type Consumer struct {
-	name    string
-	invoker Invoker
+	name       string
+	invoker    Invoker
+	configure  ConfigureFunc
+	cacheHints map[string]CacheHint
 }
 
 func NewConsumer(name string, invoker Invoker) Consumer {
@@ -39,6 +111,16 @@ func NewConsumer(name string, invoker Invoker) Consumer {
 	}
 }
 
+func (i Consumer) WithConfigure(configure ConfigureFunc) Consumer {
+	i.configure = configure
+	return i
+}
+
+func (i Consumer) WithCacheHints(hints map[string]CacheHint) Consumer {
+	i.cacheHints = hints
+	return i
+}
+
 func (i Consumer) Function() *jsonnet.NativeFunction {
 	return &jsonnet.NativeFunction{
 		Name:   fmt.Sprintf("invoke:%s", i.name),
@@ -60,16 +142,80 @@ func (i Consumer) Function() *jsonnet.NativeFunction {
 	}
 }
 
+func (i Consumer) BatchFunction() *jsonnet.NativeFunction {
+	return &jsonnet.NativeFunction{
+		Name:   fmt.Sprintf("invokeBatch:%s", i.name),
+		Params: ast.Identifiers{"calls"},
+		Func: func(input []any) (any, error) {
+			if len(input) != 1 {
+				return nil, fmt.Errorf("calls must be provided")
+			}
+			list, ok := input[0].([]any)
+			if !ok {
+				return nil, fmt.Errorf("calls must be an array")
+			}
+			calls := make([]Call, len(list))
+			for j, item := range list {
+				call, err := parseCall(item)
+				if err != nil {
+					return nil, fmt.Errorf("call %d: %w", j, err)
+				}
+				calls[j] = call
+			}
+			results, err := InvokeBatch(i.invoker, calls)
+			if err != nil {
+				return nil, err
+			}
+			values := make([]any, len(results))
+			for j, result := range results {
+				if result.Err != nil {
+					return nil, fmt.Errorf("call %d to %s failed: %w", j, calls[j].FuncName, result.Err)
+				}
+				values[j] = result.Value
+			}
+			return values, nil
+		},
+	}
+}
+
+func parseCall(item any) (Call, error) {
+	obj, ok := item.(map[string]any)
+	if !ok {
+		return Call{}, fmt.Errorf("call must be an object with funcName and args")
+	}
+	funcName, ok := obj["funcName"].(string)
+	if !ok {
+		return Call{}, fmt.Errorf("funcName must be a string")
+	}
+	args, ok := obj["args"].([]any)
+	if !ok {
+		return Call{}, fmt.Errorf("args must be an array")
+	}
+	return Call{FuncName: funcName, Args: args}, nil
+}
+
 type grpcPlugin struct {
 	plugin.Plugin
-	Impl Invoker
+	Name       string
+	Impl       Invoker
+	Configure  ConfigureFunc
+	CacheHints map[string]CacheHint
 }
 
 func (p *grpcPlugin) GRPCServer(broker *plugin.GRPCBroker, s *grpc.Server) error {
-	proto.RegisterInvokerServer(s, &grpcServerInvoker{impl: p.Impl})
+	proto.RegisterInvokerServer(s, &grpcServerInvoker{
+		name:       p.Name,
+		impl:       p.Impl,
+		configure:  p.Configure,
+		cacheHints: p.CacheHints,
+		hosts:      newRemoteHosts(broker),
+	})
 	return nil
 }
 
 func (p *grpcPlugin) GRPCClient(ctx context.Context, broker *plugin.GRPCBroker, c *grpc.ClientConn) (any, error) {
-	return &grpcClientInvoker{client: proto.NewInvokerClient(c)}, nil
+	return &grpcClientInvoker{
+		client: proto.NewInvokerClient(c),
+		hosts:  newHostRegistry(broker),
+	}, nil
 }
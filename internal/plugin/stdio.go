@@ -0,0 +1,241 @@
+package plugin
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/hashicorp/go-hclog"
+)
+
+const stdioShutdownTimeout = 2 * time.Second
+
+type stdioInvokeRequest struct {
+	ID       uint64 `json:"id"`
+	FuncName string `json:"funcName"`
+	Args     []any  `json:"args"`
+}
+
+type stdioConfigureRequest struct {
+	ID        uint64         `json:"id"`
+	Configure map[string]any `json:"configure"`
+}
+
+type stdioReleaseRequest struct {
+	ID      uint64   `json:"id"`
+	Release []string `json:"release"`
+}
+
+type stdioResponse struct {
+	ID        uint64          `json:"id"`
+	Value     json.RawMessage `json:"value"`
+	Error     string          `json:"error"`
+	Retryable bool            `json:"retryable"`
+}
+
+type stdioClient struct {
+	name    string
+	cmd     *exec.Cmd
+	limiter *limiter
+	stdin   io.WriteCloser
+	stdout  *bufio.Reader
+	logger  hclog.Logger
+	exited  chan struct{}
+	broken  atomic.Bool
+
+	mu     sync.Mutex
+	nextID uint64
+}
+
+func NewStdioInvoker(
+	name string,
+	path string,
+	config ProcessConfig,
+) (InvokeCloser, error) {
+	err := checkPluginPath(path)
+	if err != nil {
+		return nil, err
+	}
+	return newSupervisor(name, func(stderr io.Writer) (process, error) {
+		err := verifyChecksum(name, path, config.SHA256)
+		if err != nil {
+			return nil, err
+		}
+		var l *limiter
+		if config.confined() {
+			l, err = newLimiter(name, config.Limits)
+			if err != nil {
+				return nil, err
+			}
+		}
+		cmd, err := config.command(name, path)
+		if err != nil {
+			return nil, err
+		}
+		return startStdio(name, cmd, config.Settings, l, stderr)
+	}), nil
+}
+
+func startStdio(name string, cmd *exec.Cmd, settings map[string]any, l *limiter, stderr io.Writer) (process, error) {
+	logger := newLogger().Named(name)
+	cmd.Stderr = io.MultiWriter(stderr, logger.StandardWriter(&hclog.StandardLoggerOptions{
+		ForceLevel: hclog.Debug,
+	}))
+	if l != nil {
+		cmd.Stderr = io.MultiWriter(cmd.Stderr, l.stderrWriter())
+	}
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if l != nil {
+		err = l.start(cmd)
+	} else {
+		err = cmd.Start()
+	}
+	if err != nil {
+		return nil, err
+	}
+	c := &stdioClient{
+		name:    name,
+		cmd:     cmd,
+		limiter: l,
+		stdin:   stdin,
+		stdout:  bufio.NewReader(stdout),
+		logger:  logger,
+		exited:  make(chan struct{}),
+	}
+	go c.wait()
+	if settings != nil {
+		_, err = c.call(func(id uint64) any {
+			return stdioConfigureRequest{ID: id, Configure: settings}
+		})
+		if err != nil {
+			_ = c.Close()
+			return nil, fmt.Errorf("failed to configure plugin %s: %w", name, err)
+		}
+	}
+	return c, nil
+}
+
+func (c *stdioClient) wait() {
+	err := c.cmd.Wait()
+	if err != nil {
+		c.logger.Debug("plugin exited", "error", err)
+	}
+	if c.limiter != nil {
+		c.limiter.exited(c.cmd.ProcessState)
+	}
+	close(c.exited)
+}
+
+func (c *stdioClient) Invoke(funcName string, args []any) (any, error) {
+	if args == nil {
+		args = []any{}
+	}
+	return c.call(func(id uint64) any {
+		return stdioInvokeRequest{ID: id, FuncName: funcName, Args: args}
+	})
+}
+
+func (c *stdioClient) call(newRequest func(id uint64) any) (any, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.nextID++
+	id := c.nextID
+	b, err := json.Marshal(newRequest(id))
+	if err != nil {
+		return nil, err
+	}
+	_, err = c.stdin.Write(append(b, '\n'))
+	if err != nil {
+		c.broken.Store(true)
+		return nil, fmt.Errorf("failed to send request to plugin %s: %w", c.name, err)
+	}
+
+	line, err := c.stdout.ReadBytes('\n')
+	if err != nil {
+		c.broken.Store(true)
+		if errors.Is(err, io.EOF) {
+			return nil, fmt.Errorf("plugin %s closed its output", c.name)
+		}
+		return nil, fmt.Errorf("failed to read response from plugin %s: %w", c.name, err)
+	}
+	var resp stdioResponse
+	err = json.Unmarshal(line, &resp)
+	if err != nil {
+		return nil, fmt.Errorf("plugin %s sent an invalid response: %w", c.name, err)
+	}
+	if resp.ID != id {
+		c.broken.Store(true)
+		return nil, fmt.Errorf("plugin %s answered request %d, expected %d", c.name, resp.ID, id)
+	}
+	if resp.Error != "" {
+		return nil, remoteError(resp.Error, resp.Retryable)
+	}
+	var res any
+	if len(resp.Value) > 0 {
+		err = json.Unmarshal(resp.Value, &res)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return res, nil
+}
+
+func (c *stdioClient) Release(handles []string) error {
+	if handles == nil {
+		handles = []string{}
+	}
+	_, err := c.call(func(id uint64) any {
+		return stdioReleaseRequest{ID: id, Release: handles}
+	})
+	return err
+}
+
+func (c *stdioClient) Exited() bool {
+	if c.broken.Load() {
+		return true
+	}
+	select {
+	case <-c.exited:
+		return true
+	default:
+		return false
+	}
+}
+
+func (c *stdioClient) Close() error {
+	err := c.stdin.Close()
+	if err != nil {
+		c.logger.Debug("failed to close plugin input", "error", err)
+	}
+	select {
+	case <-c.exited:
+	case <-time.After(stdioShutdownTimeout):
+		err = c.cmd.Process.Kill()
+		if err != nil {
+			return err
+		}
+		<-c.exited
+	}
+	return nil
+}
+
+func (c *stdioClient) limitExceeded() string {
+	if c.limiter == nil {
+		return ""
+	}
+	return c.limiter.limitExceeded()
+}
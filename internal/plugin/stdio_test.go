@@ -0,0 +1,253 @@
+package plugin
+
+import (
+	"bufio"
+	"encoding/json"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const stdioTestEnv = "JPOET_TEST_STDIO_PLUGIN"
+
+func TestMain(m *testing.M) {
+	Trampoline()
+	if os.Getenv(stdioTestEnv) == "1" {
+		serveStdioTestPlugin()
+		os.Exit(0)
+	}
+	if os.Getenv(grpcTestEnv) == "1" {
+		serveGRPCTestPlugin()
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+// stdioTestRequest decodes all kinds of requests that a stdio plugin receives.
+type stdioTestRequest struct {
+	ID        uint64         `json:"id"`
+	FuncName  string         `json:"funcName"`
+	Args      []any          `json:"args"`
+	Configure map[string]any `json:"configure"`
+	Release   []string       `json:"release"`
+}
+
+// serveStdioTestPlugin implements the stdio protocol the way a non-Go plugin would.
+func serveStdioTestPlugin() {
+	var settings map[string]any
+	scanner := bufio.NewScanner(os.Stdin)
+	for scanner.Scan() {
+		var req stdioTestRequest
+		err := json.Unmarshal(scanner.Bytes(), &req)
+		if err != nil {
+			os.Exit(1)
+		}
+		resp := map[string]any{"id": req.ID}
+		if req.Configure != nil {
+			settings = req.Configure
+			b, _ := json.Marshal(resp)
+			_, _ = os.Stdout.Write(append(b, '\n'))
+			continue
+		}
+		switch req.FuncName {
+		case "echo":
+			resp["value"] = req.Args
+		case "hasArgs":
+			resp["value"] = req.Args != nil
+		case "upper":
+			resp["value"] = strings.ToUpper(req.Args[0].(string))
+		case "setting":
+			resp["value"] = settings[req.Args[0].(string)]
+		case "env":
+			resp["value"] = os.Getenv(req.Args[0].(string))
+		case "read":
+			b, err := os.ReadFile(req.Args[0].(string))
+			if err != nil {
+				resp["error"] = err.Error()
+			} else {
+				resp["value"] = string(b)
+			}
+		case "dial":
+			conn, err := net.Dial("tcp", req.Args[0].(string))
+			if err != nil {
+				resp["error"] = err.Error()
+			} else {
+				_ = conn.Close()
+				resp["value"] = true
+			}
+		case "spin":
+			for {
+			}
+		case "open":
+			for i := 0; i < int(req.Args[0].(float64)); i++ {
+				_, err := os.Open(os.Args[0])
+				if err != nil {
+					_, _ = os.Stderr.WriteString("fatal: " + err.Error() + "\n")
+					os.Exit(2)
+				}
+			}
+			resp["value"] = true
+		case "busy":
+			resp["error"] = "busy"
+			resp["retryable"] = true
+		case "crashOnce":
+			_, err := os.Stat(req.Args[0].(string))
+			if err != nil {
+				_ = os.WriteFile(req.Args[0].(string), nil, 0o600)
+				os.Exit(2)
+			}
+			resp["value"] = "recovered"
+		case "crash":
+			_, _ = os.Stderr.WriteString("panic: " + req.Args[0].(string) + "\n")
+			os.Exit(2)
+		default:
+			resp["error"] = "no such function: " + req.FuncName
+		}
+		b, _ := json.Marshal(resp)
+		_, _ = os.Stdout.Write(append(b, '\n'))
+	}
+}
+
+func newStdioTestInvoker(t *testing.T) InvokeCloser {
+	return newConfiguredStdioTestInvoker(t, ProcessConfig{})
+}
+
+func newConfiguredStdioTestInvoker(t *testing.T, config ProcessConfig) InvokeCloser {
+	t.Helper()
+	config.PassEnv = append(config.PassEnv, stdioTestEnv)
+	t.Setenv(stdioTestEnv, "1")
+	exe, err := os.Executable()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	path := filepath.Join(t.TempDir(), "jsonnet-plugin-test")
+	err = os.Symlink(exe, path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	invoker, err := NewStdioInvoker("test", path, config)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	t.Cleanup(func() {
+		_ = invoker.Close()
+	})
+	return invoker
+}
+
+func TestStdioInvoker_Invoke(t *testing.T) {
+	invoker := newStdioTestInvoker(t)
+
+	res, err := invoker.Invoke("upper", []any{"hello"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if res != "HELLO" {
+		t.Errorf("expected HELLO, got %v", res)
+	}
+
+	res, err = invoker.Invoke("echo", []any{"a", 1.0, map[string]any{"b": true}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	b, _ := json.Marshal(res)
+	if string(b) != `["a",1,{"b":true}]` {
+		t.Errorf("unexpected result: %s", b)
+	}
+}
+
+func TestStdioInvoker_NoArgs(t *testing.T) {
+	invoker := newStdioTestInvoker(t)
+
+	res, err := invoker.Invoke("hasArgs", nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if res != true {
+		t.Errorf("expected args to be sent as an empty array")
+	}
+}
+
+func TestStdioRequests(t *testing.T) {
+	for expected, req := range map[string]any{
+		`{"id":1,"funcName":"now","args":[]}`: stdioInvokeRequest{ID: 1, FuncName: "now", Args: []any{}},
+		`{"id":2,"configure":{}}`:             stdioConfigureRequest{ID: 2, Configure: map[string]any{}},
+		`{"id":3,"release":["h1"]}`:           stdioReleaseRequest{ID: 3, Release: []string{"h1"}},
+	} {
+		b, err := json.Marshal(req)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if string(b) != expected {
+			t.Errorf("expected %s, got %s", expected, b)
+		}
+	}
+}
+
+func TestStdioInvoker_Error(t *testing.T) {
+	invoker := newStdioTestInvoker(t)
+
+	_, err := invoker.Invoke("missing", []any{})
+	if err == nil || !strings.Contains(err.Error(), "no such function: missing") {
+		t.Errorf("expected plugin error, got: %v", err)
+	}
+}
+
+func TestStdioInvoker_Retryable(t *testing.T) {
+	invoker := newStdioTestInvoker(t)
+
+	_, err := invoker.Invoke("busy", []any{})
+	if err == nil || !IsRetryable(err) {
+		t.Errorf("expected retryable error, got: %v", err)
+	}
+	_, err = invoker.Invoke("missing", []any{})
+	if IsRetryable(err) {
+		t.Errorf("expected error not to be retryable, got: %v", err)
+	}
+}
+
+func TestStdioInvoker_InvalidPath(t *testing.T) {
+	_, err := NewStdioInvoker("test", filepath.Join(t.TempDir(), "test"), ProcessConfig{})
+	if err == nil || !strings.Contains(err.Error(), "jsonnet-plugin") {
+		t.Errorf("expected path error, got: %v", err)
+	}
+}
+
+func TestStdioInvoker_Configure(t *testing.T) {
+	invoker := newConfiguredStdioTestInvoker(t, ProcessConfig{
+		Settings: map[string]any{"endpoint": "http://localhost"},
+	})
+
+	res, err := invoker.Invoke("setting", []any{"endpoint"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if res != "http://localhost" {
+		t.Errorf("expected configured setting, got %v", res)
+	}
+}
+
+func TestStdioInvoker_Env(t *testing.T) {
+	t.Setenv("JPOET_TEST_SECRET", "secret")
+	invoker := newConfiguredStdioTestInvoker(t, ProcessConfig{
+		Env: map[string]string{"JPOET_TEST_FLAG": "on"},
+	})
+
+	res, err := invoker.Invoke("env", []any{"JPOET_TEST_FLAG"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if res != "on" {
+		t.Errorf("expected configured env variable, got %v", res)
+	}
+
+	res, err = invoker.Invoke("env", []any{"JPOET_TEST_SECRET"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if res != "" {
+		t.Errorf("expected host env variable not to be passed, got %v", res)
+	}
+}
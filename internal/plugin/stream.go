@@ -0,0 +1,187 @@
+package plugin
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"regexp"
+	"strconv"
+
+	"github.com/marcbran/jpoet/internal/plugin/proto"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	DefaultMaxPayloadSize = 64 << 20
+	streamChunkSize       = 1 << 20
+	messageOverhead       = 64 << 10
+)
+
+func (c ProcessConfig) maxPayloadSize() int {
+	if c.MaxPayloadSize > 0 {
+		return c.MaxPayloadSize
+	}
+	return DefaultMaxPayloadSize
+}
+
+func (c ProcessConfig) dialOptions() []grpc.DialOption {
+	size := min(c.maxPayloadSize()+messageOverhead, math.MaxInt32)
+	return []grpc.DialOption{
+		grpc.WithDefaultCallOptions(
+			grpc.MaxCallRecvMsgSize(size),
+			grpc.MaxCallSendMsgSize(size),
+		),
+	}
+}
+
+func grpcServer(opts []grpc.ServerOption) *grpc.Server {
+	return grpc.NewServer(append(opts, grpc.MaxRecvMsgSize(math.MaxInt32))...)
+}
+
+type payloadSizeError struct {
+	plugin  string
+	call    string
+	payload string
+	size    int
+	limit   int
+}
+
+func (e payloadSizeError) Error() string {
+	return fmt.Sprintf(
+		"%s of %d bytes exceeds the limit of %d bytes when invoking %s of plugin %s",
+		e.payload, e.size, e.limit, e.call, e.plugin,
+	)
+}
+
+var messageSizePattern = regexp.MustCompile(`larger than max \((\d+) vs\. (\d+)\)`)
+
+func (c grpcClientInvoker) sizeError(call string, err error) error {
+	if status.Code(err) != codes.ResourceExhausted {
+		return err
+	}
+	match := messageSizePattern.FindStringSubmatch(status.Convert(err).Message())
+	if match == nil {
+		return err
+	}
+	size, _ := strconv.Atoi(match[1])
+	limit, _ := strconv.Atoi(match[2])
+	return payloadSizeError{
+		plugin:  c.name,
+		call:    call,
+		payload: "message",
+		size:    size,
+		limit:   limit,
+	}
+}
+
+func (c grpcClientInvoker) invokeStream(funcName string, args []byte, ref hostRef) (any, error) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	stream, err := c.client.InvokeStream(ctx)
+	if err != nil {
+		return nil, err
+	}
+	err = sendChunks(&proto.InvokeChunk{FuncName: funcName, HostId: ref.id, HostScope: ref.scope}, args, stream.Send)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+	err = stream.CloseSend()
+	if err != nil {
+		return nil, err
+	}
+	var value []byte
+	size := 0
+	for {
+		chunk, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if chunk.Error != "" {
+			return nil, remoteError(chunk.Error, chunk.Retryable)
+		}
+		size += len(chunk.Data)
+		if size > c.maxPayloadSize {
+			value = nil
+			continue
+		}
+		value = append(value, chunk.Data...)
+	}
+	if size > c.maxPayloadSize {
+		return nil, payloadSizeError{
+			plugin:  c.name,
+			call:    funcName,
+			payload: "result",
+			size:    size,
+			limit:   c.maxPayloadSize,
+		}
+	}
+	var res any
+	err = json.Unmarshal(value, &res)
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+func (s grpcServerInvoker) InvokeStream(stream grpc.BidiStreamingServer[proto.InvokeChunk, proto.InvokeChunk]) error {
+	var head *proto.InvokeChunk
+	var args []byte
+	for {
+		chunk, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		if head == nil {
+			head = chunk
+		}
+		args = append(args, chunk.Data...)
+	}
+	if head == nil {
+		return status.Errorf(codes.InvalidArgument, "missing function name")
+	}
+	var value []byte
+	host, err := s.hosts.host(head.HostId, head.HostScope)
+	if err == nil {
+		var parsed []any
+		err = json.Unmarshal(args, &parsed)
+		if err == nil {
+			var res any
+			res, err = InvokeWithHost(s.impl, host, head.FuncName, parsed)
+			if err == nil {
+				value, err = json.Marshal(res)
+			}
+		}
+	}
+	if err != nil {
+		return stream.Send(&proto.InvokeChunk{Error: err.Error(), Retryable: IsRetryable(err)})
+	}
+	return sendChunks(&proto.InvokeChunk{}, value, stream.Send)
+}
+
+func sendChunks(head *proto.InvokeChunk, data []byte, send func(*proto.InvokeChunk) error) error {
+	chunk := head
+	for {
+		n := min(len(data), streamChunkSize)
+		chunk.Data = data[:n]
+		err := send(chunk)
+		if err != nil {
+			return err
+		}
+		data = data[n:]
+		if len(data) == 0 {
+			return nil
+		}
+		chunk = &proto.InvokeChunk{}
+	}
+}
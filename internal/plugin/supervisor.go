@@ -0,0 +1,230 @@
+package plugin
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+)
+
+const (
+	maxRestarts    = 3
+	maxStderrBytes = 4096
+)
+
+type process interface {
+	InvokeCloser
+	Exited() bool
+}
+
+type startFunc func(stderr io.Writer) (process, error)
+
+type supervisor struct {
+	name  string
+	start startFunc
+
+	mu         sync.Mutex
+	proc       process
+	generation uint64
+	aborted    process
+	stderr     *stderrBuffer
+	crashes    int
+	closed     bool
+}
+
+func newSupervisor(name string, start startFunc) *supervisor {
+	return &supervisor{
+		name:   name,
+		start:  start,
+		stderr: &stderrBuffer{},
+	}
+}
+
+func (s *supervisor) Invoke(funcName string, args []any) (any, error) {
+	return s.InvokeWithHost(nil, funcName, args)
+}
+
+func (s *supervisor) InvokeWithHost(host Host, funcName string, args []any) (any, error) {
+	var res any
+	err := s.run(fmt.Sprintf("invoking %s", funcName), func(proc process) error {
+		var err error
+		res, err = InvokeWithHost(proc, host, funcName, args)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+func (s *supervisor) InvokeBatch(calls []Call) ([]Result, error) {
+	return s.InvokeBatchWithHost(nil, calls)
+}
+
+func (s *supervisor) InvokeBatchWithHost(host Host, calls []Call) ([]Result, error) {
+	var results []Result
+	err := s.run(fmt.Sprintf("invoking a batch of %d calls", len(calls)), func(proc process) error {
+		var err error
+		results, err = InvokeBatchWithHost(proc, host, calls)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+func (s *supervisor) run(what string, f func(proc process) error) error {
+	for retried := false; ; retried = true {
+		proc, err := s.process()
+		if err != nil {
+			return err
+		}
+		err = f(proc)
+		if err == nil || !proc.Exited() || s.wasAborted(proc) {
+			return err
+		}
+		limit := ""
+		if p, ok := proc.(limitedProcess); ok {
+			limit = p.limitExceeded()
+		}
+		err = s.crashed(proc, limit, fmt.Errorf("plugin %s crashed while %s: %w", s.name, what, err))
+		if retried || limit != "" {
+			return err
+		}
+	}
+}
+
+func (s *supervisor) Abort() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.proc == nil {
+		return
+	}
+	proc := s.proc
+	s.aborted = proc
+	s.proc = nil
+	go func() {
+		_ = proc.Close()
+	}()
+}
+
+func (s *supervisor) wasAborted(proc process) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.aborted == proc
+}
+
+func (s *supervisor) Release(handles []string) error {
+	s.mu.Lock()
+	proc := s.proc
+	s.mu.Unlock()
+	if proc == nil || proc.Exited() {
+		return nil
+	}
+	return Release(proc, handles)
+}
+
+func (s *supervisor) Functions() []FunctionInfo {
+	s.mu.Lock()
+	proc := s.proc
+	s.mu.Unlock()
+	d, ok := proc.(describer)
+	if !ok {
+		return nil
+	}
+	return d.Functions()
+}
+
+func (s *supervisor) Generation() uint64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.generation
+}
+
+func (s *supervisor) process() (process, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return nil, fmt.Errorf("plugin %s is closed", s.name)
+	}
+	if s.proc != nil && !s.proc.Exited() {
+		return s.proc, nil
+	}
+	if s.proc != nil {
+		s.crashes++
+		s.proc = nil
+	}
+	if s.crashes > maxRestarts {
+		return nil, s.withStderr(fmt.Errorf("plugin %s crashed %d times and will not be restarted", s.name, s.crashes))
+	}
+	s.stderr.Reset()
+	proc, err := s.start(s.stderr)
+	if err != nil {
+		return nil, s.withStderr(fmt.Errorf("failed to start plugin %s: %w", s.name, err))
+	}
+	s.proc = proc
+	s.generation++
+	return proc, nil
+}
+
+func (s *supervisor) crashed(proc process, limit string, err error) error {
+	if limit != "" {
+		err = fmt.Errorf("%w\nplugin %s was stopped because it exceeded its %s", err, s.name, limit)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.proc == proc {
+		_ = proc.Close()
+		s.crashes++
+		s.proc = nil
+	}
+	return s.withStderr(err)
+}
+
+func (s *supervisor) withStderr(err error) error {
+	stderr := strings.TrimSpace(s.stderr.String())
+	if stderr == "" {
+		return err
+	}
+	return fmt.Errorf("%w\nplugin stderr:\n%s", err, stderr)
+}
+
+func (s *supervisor) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.closed = true
+	if s.proc == nil {
+		return nil
+	}
+	err := s.proc.Close()
+	s.proc = nil
+	return err
+}
+
+type stderrBuffer struct {
+	mu  sync.Mutex
+	buf []byte
+}
+
+func (b *stderrBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.buf = append(b.buf, p...)
+	if len(b.buf) > maxStderrBytes {
+		b.buf = b.buf[len(b.buf)-maxStderrBytes:]
+	}
+	return len(p), nil
+}
+
+func (b *stderrBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return string(b.buf)
+}
+
+func (b *stderrBuffer) Reset() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.buf = nil
+}
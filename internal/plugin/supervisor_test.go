@@ -0,0 +1,130 @@
+package plugin
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSupervisor_LazyStart(t *testing.T) {
+	invoker, err := NewStdioInvoker("test", filepath.Join(t.TempDir(), "jsonnet-plugin-test"), ProcessConfig{})
+	if err != nil {
+		t.Fatalf("expected plugin not to be started before the first invocation, got: %v", err)
+	}
+	defer func() {
+		_ = invoker.Close()
+	}()
+
+	_, err = invoker.Invoke("upper", []any{"hello"})
+	if err == nil || !strings.Contains(err.Error(), "failed to start plugin test") {
+		t.Errorf("expected start error on first invocation, got: %v", err)
+	}
+}
+
+func TestSupervisor_RestartsCrashedPlugin(t *testing.T) {
+	invoker := newStdioTestInvoker(t)
+
+	_, err := invoker.Invoke("crash", []any{"boom"})
+	if err == nil || !strings.Contains(err.Error(), "plugin test crashed while invoking crash") {
+		t.Fatalf("expected crash error, got: %v", err)
+	}
+	if !strings.Contains(err.Error(), "panic: boom") {
+		t.Errorf("expected crash error to contain plugin stderr, got: %v", err)
+	}
+
+	res, err := invoker.Invoke("upper", []any{"hello"})
+	if err != nil {
+		t.Fatalf("expected plugin to be restarted, got: %v", err)
+	}
+	if res != "HELLO" {
+		t.Errorf("expected HELLO, got %v", res)
+	}
+}
+
+func TestSupervisor_GivesUpAfterMaxRestarts(t *testing.T) {
+	invoker := newStdioTestInvoker(t)
+
+	for i := 0; i <= maxRestarts; i++ {
+		_, err := invoker.Invoke("crash", []any{"boom"})
+		if err == nil {
+			t.Fatalf("expected crash error")
+		}
+	}
+
+	_, err := invoker.Invoke("upper", []any{"hello"})
+	if err == nil || !strings.Contains(err.Error(), "will not be restarted") {
+		t.Errorf("expected plugin to stay down, got: %v", err)
+	}
+}
+
+func TestSupervisor_RetriesCallAfterCrash(t *testing.T) {
+	invoker := newStdioTestInvoker(t)
+
+	res, err := invoker.Invoke("crashOnce", []any{filepath.Join(t.TempDir(), "crashed")})
+	if err != nil {
+		t.Fatalf("expected call to be retried on the restarted plugin, got: %v", err)
+	}
+	if res != "recovered" {
+		t.Errorf("expected recovered, got %v", res)
+	}
+}
+
+func TestSupervisor_DoesNotRetryStartFailures(t *testing.T) {
+	invoker, err := NewStdioInvoker("test", filepath.Join(t.TempDir(), "jsonnet-plugin-test"), ProcessConfig{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer func() {
+		_ = invoker.Close()
+	}()
+
+	for i := 0; i <= maxRestarts+1; i++ {
+		_, err = invoker.Invoke("upper", []any{"hello"})
+		if err == nil || !strings.Contains(err.Error(), "failed to start plugin test") {
+			t.Fatalf("expected start error, got: %v", err)
+		}
+	}
+}
+
+func TestSupervisor_GenerationChangesOnRestart(t *testing.T) {
+	invoker := newStdioTestInvoker(t)
+
+	_, err := invoker.Invoke("upper", []any{"hello"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	generation := Generation(invoker)
+	_, _ = invoker.Invoke("crash", []any{"boom"})
+	_, err = invoker.Invoke("upper", []any{"hello"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if Generation(invoker) == generation {
+		t.Errorf("expected generation %d to change after the restart", generation)
+	}
+}
+
+func TestSupervisor_AbortRestartsBlockedPlugin(t *testing.T) {
+	invoker := newStdioTestInvoker(t)
+
+	blocked := make(chan error)
+	go func() {
+		_, err := invoker.Invoke("spin", []any{})
+		blocked <- err
+	}()
+	time.Sleep(100 * time.Millisecond)
+	Abort(invoker)
+
+	res, err := invoker.Invoke("upper", []any{"hello"})
+	if err != nil {
+		t.Fatalf("expected plugin to be restarted, got: %v", err)
+	}
+	if res != "HELLO" {
+		t.Errorf("expected HELLO, got %v", res)
+	}
+	err = <-blocked
+	if err == nil {
+		t.Errorf("expected the aborted call to fail")
+	}
+}
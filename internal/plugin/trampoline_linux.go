@@ -0,0 +1,104 @@
+package plugin
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+	"sync/atomic"
+	"syscall"
+)
+
+const (
+	trampolineArg   = "__jpoet_trampoline"
+	trampolineEnv   = "JPOET_TRAMPOLINE"
+	trampolineProbe = "probe"
+)
+
+type trampolineSpec struct {
+	Path    string       `json:"path"`
+	Sandbox *sandboxSpec `json:"sandbox,omitempty"`
+	Rlimits []rlimit     `json:"rlimits,omitempty"`
+}
+
+var trampolineEnabled atomic.Bool
+
+// Trampoline has to be called first thing in main for plugins to be sandboxed and limited before they run.
+func Trampoline() {
+	if len(os.Args) != 2 || os.Args[1] != trampolineArg {
+		trampolineEnabled.Store(true)
+		return
+	}
+	value := os.Getenv(trampolineEnv)
+	if value == trampolineProbe {
+		os.Exit(0)
+	}
+	err := execTrampoline(value)
+	_, _ = fmt.Fprintf(os.Stderr, "failed to start plugin: %v\n", err)
+	os.Exit(1)
+}
+
+func throughTrampoline(cmd *exec.Cmd, update func(spec *trampolineSpec)) error {
+	spec := trampolineSpec{Path: cmd.Path}
+	i := -1
+	if len(cmd.Args) == 2 && cmd.Args[1] == trampolineArg {
+		for j, e := range cmd.Env {
+			value, ok := strings.CutPrefix(e, trampolineEnv+"=")
+			if ok {
+				i = j
+				err := json.Unmarshal([]byte(value), &spec)
+				if err != nil {
+					return err
+				}
+			}
+		}
+	}
+	update(&spec)
+	b, err := json.Marshal(spec)
+	if err != nil {
+		return err
+	}
+	env := fmt.Sprintf("%s=%s", trampolineEnv, b)
+	if i >= 0 {
+		cmd.Env[i] = env
+		return nil
+	}
+	self, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	cmd.Path = self
+	cmd.Args = []string{self, trampolineArg}
+	cmd.Env = append(cmd.Env, env)
+	return nil
+}
+
+func execTrampoline(value string) error {
+	runtime.LockOSThread()
+	var spec trampolineSpec
+	err := json.Unmarshal([]byte(value), &spec)
+	if err != nil {
+		return err
+	}
+	for _, r := range spec.Rlimits {
+		err = syscall.Setrlimit(r.Resource, &syscall.Rlimit{Cur: r.Soft, Max: r.Hard})
+		if err != nil {
+			return fmt.Errorf("failed to apply %s limit: %w", r.Name, err)
+		}
+	}
+	if spec.Sandbox != nil {
+		err = restrictSelf(spec.Path, *spec.Sandbox)
+		if err != nil {
+			return err
+		}
+	}
+	var env []string
+	for _, e := range os.Environ() {
+		if !strings.HasPrefix(e, trampolineEnv+"=") {
+			env = append(env, e)
+		}
+	}
+	return syscall.Exec(spec.Path, []string{spec.Path}, env)
+}
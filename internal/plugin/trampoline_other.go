@@ -0,0 +1,6 @@
+//go:build !linux
+
+package plugin
+
+// Trampoline has to be called first thing in main for plugins to be sandboxed and limited before they run.
+func Trampoline() {}
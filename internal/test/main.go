@@ -2,7 +2,6 @@ package test
 
 import (
 	"embed"
-	"encoding/json"
 	"errors"
 	"fmt"
 	"github.com/google/go-jsonnet"
@@ -46,7 +45,7 @@ type Result struct {
 //go:embed lib
 var lib embed.FS
 
-func RunDir(dirname string) (*Run, error) {
+func RunDir(dirname string, plugins ...*jpoet.Plugin) (*Run, error) {
 	var run Run
 	var runErr error
 	err := filepath.WalkDir(dirname, func(path string, d fs.DirEntry, err error) error {
@@ -56,7 +55,7 @@ func RunDir(dirname string) (*Run, error) {
 		if !strings.HasSuffix(path, "_tests.libsonnet") {
 			return nil
 		}
-		r, err := RunFile(path)
+		r, err := RunFile(path, plugins...)
 		if err != nil {
 			runErr = err
 			_, err := os.Stderr.WriteString(err.Error())
@@ -79,24 +78,20 @@ func RunDir(dirname string) (*Run, error) {
 	return &run, nil
 }
 
-func RunFile(filename string) (*Run, error) {
-	vm := jsonnet.MakeVM()
-	vm.Importer(jpoet.CompoundImporter{
-		Importers: []jsonnet.Importer{
-			&jpoet.FSImporter{Fs: lib},
-			&jsonnet.FileImporter{},
-		},
-	})
-	res, err := vm.EvaluateAnonymousSnippet("main.jsonnet", fmt.Sprintf(`
+func RunFile(filename string, plugins ...*jpoet.Plugin) (*Run, error) {
+	opts := []jpoet.Option{
+		jpoet.FSImport(lib),
+		jpoet.Importer(&jsonnet.FileImporter{}),
+		jpoet.SnippetInput("main.jsonnet", fmt.Sprintf(`
 		local tests = import '%s';
 		local lib = import 'lib/main.libsonnet';
 		lib.runTests(tests)
-	`, filename))
-	if err != nil {
-		return nil, err
+	`, filename)),
+		jpoet.Serialize(false),
 	}
+	opts = append(opts, jpoet.WithPluginFunctions(plugins...))
 	var run Run
-	err = json.Unmarshal([]byte(res), &run)
+	err := jpoet.Eval(append(opts, jpoet.ValueOutput(&run))...)
 	if err != nil {
 		return nil, err
 	}
@@ -2,8 +2,10 @@ package main
 
 import (
 	"github.com/marcbran/jpoet/cmd"
+	"github.com/marcbran/jpoet/pkg/jpoet"
 )
 
 func main() {
+	jpoet.Trampoline()
 	cmd.Execute()
 }
@@ -0,0 +1,223 @@
+package jpoet
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+type AuditLog struct {
+	hash    bool
+	hashKey []byte
+	rules   []RedactRule
+
+	mu sync.Mutex
+	w  io.Writer
+}
+
+type AuditEntry struct {
+	Time       time.Time `json:"time"`
+	Plugin     string    `json:"plugin"`
+	Version    string    `json:"version,omitempty"`
+	Function   string    `json:"function"`
+	Args       any       `json:"args,omitempty"`
+	ArgsHash   string    `json:"argsHash,omitempty"`
+	Result     any       `json:"result,omitempty"`
+	ResultHash string    `json:"resultHash,omitempty"`
+	DurationMs float64   `json:"durationMs"`
+	Outcome    string    `json:"outcome"`
+	Error      string    `json:"error,omitempty"`
+}
+
+const Redacted = "[REDACTED]"
+
+type RedactRule struct {
+	Function *regexp.Regexp
+	Key      *regexp.Regexp
+	Value    *regexp.Regexp
+}
+
+var DefaultRedactRules = []RedactRule{
+	{Key: regexp.MustCompile(`(?i)passw(or)?d|secret|token|api[_-]?key|credential|private[_-]?key|authorization`)},
+	{Value: regexp.MustCompile(`\b(gh[pousr]_\w+|github_pat_\w+|xox[abprs]-[\w-]+|sk-[\w-]+|AKIA[0-9A-Z]{16}|eyJ[\w-]+\.[\w-]+\.[\w-]+)|(?s)-----BEGIN [A-Z ]*PRIVATE KEY-----.*?(-----END [A-Z ]*PRIVATE KEY-----|$)`)},
+}
+
+func ParseRedactRule(s string) (RedactRule, error) {
+	kind, pattern, ok := strings.Cut(s, "=")
+	if !ok {
+		return RedactRule{}, fmt.Errorf("expected kind=pattern, got %q", s)
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return RedactRule{}, fmt.Errorf("invalid pattern of redaction rule %q: %w", s, err)
+	}
+	switch kind {
+	case "function":
+		return RedactRule{Function: re}, nil
+	case "key":
+		return RedactRule{Key: re}, nil
+	case "value":
+		return RedactRule{Value: re}, nil
+	default:
+		return RedactRule{}, fmt.Errorf("unknown kind of redaction rule %q, expected one of function, key or value", kind)
+	}
+}
+
+type AuditOption func(*AuditLog)
+
+func AuditRedact(rules ...RedactRule) AuditOption {
+	return func(l *AuditLog) {
+		l.rules = append(l.rules, rules...)
+	}
+}
+
+func AuditHash(hash bool) AuditOption {
+	return func(l *AuditLog) {
+		l.hash = hash
+	}
+}
+
+func NewAuditLog(w io.Writer, opts ...AuditOption) *AuditLog {
+	l := &AuditLog{
+		w:       w,
+		hashKey: []byte(rand.Text()),
+		rules:   slices.Clone(DefaultRedactRules),
+	}
+	for _, opt := range opts {
+		opt(l)
+	}
+	return l
+}
+
+func (l *AuditLog) Middleware() PluginMiddleware {
+	return func(info PluginInfo) Middleware {
+		return HookMiddleware(func(next Invoker, funcName string, args []any) (any, error) {
+			start := time.Now()
+			result, err := next.Invoke(funcName, args)
+			werr := l.write(info, start, funcName, args, result, err)
+			if werr != nil {
+				return nil, fmt.Errorf("failed to write audit log: %w", werr)
+			}
+			return result, err
+		})
+	}
+}
+
+func (l *AuditLog) write(info PluginInfo, start time.Time, funcName string, args []any, result any, err error) error {
+	entry := AuditEntry{
+		Time:       start.UTC(),
+		Plugin:     info.Name,
+		Version:    info.Version,
+		Function:   funcName,
+		DurationMs: float64(time.Since(start).Microseconds()) / 1000,
+		Outcome:    "ok",
+	}
+	if err != nil {
+		entry.Outcome = "error"
+		entry.Error = l.redactString(err.Error())
+	}
+	switch {
+	case l.hash:
+		hash, herr := l.hashOf(args)
+		if herr != nil {
+			return herr
+		}
+		entry.ArgsHash = hash
+		if err == nil {
+			hash, herr = l.hashOf(result)
+			if herr != nil {
+				return herr
+			}
+			entry.ResultHash = hash
+		}
+	case l.redactsFunction(funcName):
+		entry.Args = Redacted
+		if err == nil {
+			entry.Result = Redacted
+		}
+	default:
+		entry.Args = l.redact(args)
+		if err == nil {
+			entry.Result = l.redact(result)
+		}
+	}
+	b, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	_, err = l.w.Write(append(b, '\n'))
+	return err
+}
+
+func (l *AuditLog) hashOf(v any) (string, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+	mac := hmac.New(sha256.New, l.hashKey)
+	mac.Write(b)
+	return "hmac-sha256:" + hex.EncodeToString(mac.Sum(nil)), nil
+}
+
+func (l *AuditLog) redactsFunction(funcName string) bool {
+	for _, rule := range l.rules {
+		if rule.Function != nil && rule.Function.MatchString(funcName) {
+			return true
+		}
+	}
+	return false
+}
+
+func (l *AuditLog) redactsKey(key string) bool {
+	for _, rule := range l.rules {
+		if rule.Key != nil && rule.Key.MatchString(key) {
+			return true
+		}
+	}
+	return false
+}
+
+func (l *AuditLog) redactString(s string) string {
+	for _, rule := range l.rules {
+		if rule.Value != nil {
+			s = rule.Value.ReplaceAllLiteralString(s, Redacted)
+		}
+	}
+	return s
+}
+
+func (l *AuditLog) redact(v any) any {
+	switch v := v.(type) {
+	case string:
+		return l.redactString(v)
+	case []any:
+		redacted := make([]any, len(v))
+		for i, e := range v {
+			redacted[i] = l.redact(e)
+		}
+		return redacted
+	case map[string]any:
+		redacted := make(map[string]any, len(v))
+		for k, e := range v {
+			if l.redactsKey(k) {
+				redacted[k] = Redacted
+				continue
+			}
+			redacted[k] = l.redact(e)
+		}
+		return redacted
+	default:
+		return v
+	}
+}
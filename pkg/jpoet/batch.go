@@ -0,0 +1,126 @@
+package jpoet
+
+import (
+	"sync"
+
+	"github.com/marcbran/jpoet/internal/plugin"
+)
+
+type Call = plugin.Call
+
+type Result = plugin.Result
+
+type batchInvoker struct {
+	invoker    plugin.Invoker
+	middleware []Middleware
+}
+
+func (b batchInvoker) Invoke(funcName string, args []any) (any, error) {
+	invoker := b.invoker
+	for _, m := range b.middleware {
+		invoker = m(invoker)
+	}
+	return invoker.Invoke(funcName, args)
+}
+
+func (b batchInvoker) InvokeBatch(calls []Call) ([]Result, error) {
+	if len(b.middleware) == 0 {
+		return plugin.InvokeBatch(b.invoker, calls)
+	}
+	collector := &batchCollector{
+		next:   b.invoker,
+		active: len(calls),
+	}
+	invoker := plugin.Invoker(collector)
+	for _, m := range b.middleware {
+		invoker = m(invoker)
+	}
+	results := make([]Result, len(calls))
+	var wg sync.WaitGroup
+	for i, call := range calls {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer collector.done()
+			value, err := invoker.Invoke(call.FuncName, call.Args)
+			results[i] = Result{Value: value, Err: err}
+		}()
+	}
+	wg.Wait()
+	return results, nil
+}
+
+type batchCollector struct {
+	next plugin.Invoker
+
+	mu      sync.Mutex
+	active  int
+	pending []pendingCall
+}
+
+type pendingCall struct {
+	call   Call
+	result chan Result
+}
+
+func (c *batchCollector) Invoke(funcName string, args []any) (any, error) {
+	result := make(chan Result, 1)
+	c.mu.Lock()
+	c.pending = append(c.pending, pendingCall{
+		call:   Call{FuncName: funcName, Args: args},
+		result: result,
+	})
+	c.active--
+	c.flushIfIdle()
+	c.mu.Unlock()
+	r := <-result
+	return r.Value, r.Err
+}
+
+func (c *batchCollector) block() func() {
+	c.mu.Lock()
+	c.active--
+	c.flushIfIdle()
+	c.mu.Unlock()
+	return func() {
+		c.mu.Lock()
+		c.active++
+		c.mu.Unlock()
+	}
+}
+
+func (c *batchCollector) Abort() {
+	plugin.Abort(c.next)
+}
+
+func (c *batchCollector) done() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.active--
+	c.flushIfIdle()
+}
+
+func (c *batchCollector) flushIfIdle() {
+	if c.active > 0 || len(c.pending) == 0 {
+		return
+	}
+	pending := c.pending
+	c.pending = nil
+	c.active += len(pending)
+	go c.flush(pending)
+}
+
+func (c *batchCollector) flush(pending []pendingCall) {
+	calls := make([]Call, len(pending))
+	for i, p := range pending {
+		calls[i] = p.call
+	}
+	results, err := plugin.InvokeBatch(c.next, calls)
+	for i, p := range pending {
+		if err != nil {
+			p.result <- Result{Err: err}
+			continue
+		}
+		p.result <- results[i]
+	}
+}
@@ -0,0 +1,71 @@
+package jpoet
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/go-jsonnet"
+)
+
+type recordingBatchInvoker struct {
+	mu      sync.Mutex
+	batches [][]Call
+}
+
+func (r *recordingBatchInvoker) Invoke(funcName string, args []any) (any, error) {
+	return nil, fmt.Errorf("unexpected single invocation of %s", funcName)
+}
+
+func (r *recordingBatchInvoker) InvokeBatch(calls []Call) ([]Result, error) {
+	r.mu.Lock()
+	r.batches = append(r.batches, calls)
+	r.mu.Unlock()
+	results := make([]Result, len(calls))
+	for i, call := range calls {
+		results[i] = Result{Value: strings.ToUpper(call.Args[0].(string))}
+	}
+	return results, nil
+}
+
+func TestPlugin_BatchNativeFunction(t *testing.T) {
+	invoker := &recordingBatchInvoker{}
+	cache := NewCache()
+	cache.store(PluginInfo{Name: "test"}, "upper", []any{"cached"}, "FROM CACHE", time.Minute)
+	p := (&Plugin{name: "test", invoker: invoker}).WithPluginMiddleware(cache.Reader())
+
+	var out []string
+	err := Eval(
+		SnippetInput("main.jsonnet", `std.native('invokeBatch:test')([
+			{ funcName: 'upper', args: [x] }
+			for x in ['a', 'cached', 'b']
+		])`),
+		WithNativeFunction(p.BatchNativeFunction()),
+		Serialize(false),
+		ValueOutput(&out),
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if strings.Join(out, ",") != "A,FROM CACHE,B" {
+		t.Errorf("unexpected results: %v", out)
+	}
+	if len(invoker.batches) != 1 || len(invoker.batches[0]) != 2 {
+		t.Errorf("expected the two uncached calls in a single batch, got %v", invoker.batches)
+	}
+}
+
+func TestPlugin_BatchNativeFunctionError(t *testing.T) {
+	p := NewPlugin("test", []jsonnet.NativeFunction{})
+
+	err := Eval(
+		SnippetInput("main.jsonnet", `std.native('invokeBatch:test')([{ funcName: 'missing', args: [] }])`),
+		WithNativeFunction(p.BatchNativeFunction()),
+		WriterOutput(&strings.Builder{}),
+	)
+	if err == nil || !strings.Contains(err.Error(), "call 0 to missing failed") {
+		t.Errorf("expected call error, got: %v", err)
+	}
+}
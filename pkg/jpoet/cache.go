@@ -1,86 +1,227 @@
 package jpoet
 
 import (
+	"container/list"
 	"encoding/json"
 	"sync"
 	"time"
 )
 
 type cacheKey struct {
+	plugin   string
+	version  string
 	funcName string
 	args     string
 }
 
 type cacheEntry struct {
+	key       cacheKey
 	value     any
+	size      int64
 	expiresAt time.Time
 }
 
 type Cache struct {
-	mu   sync.Mutex
-	data map[cacheKey]cacheEntry
+	maxEntries    int
+	maxBytes      int64
+	sweepInterval time.Duration
+
+	mu    sync.Mutex
+	data  map[cacheKey]*list.Element
+	lru   *list.List
+	size  int64
+	stats CacheStats
+
+	stop      chan struct{}
+	closeOnce sync.Once
+}
+
+type CacheStats struct {
+	Hits        uint64
+	Misses      uint64
+	Evictions   uint64
+	Expirations uint64
+	Entries     int
+	Bytes       int64
 }
 
-func NewCache() *Cache {
-	return &Cache{
-		data: make(map[cacheKey]cacheEntry),
+type CacheOption func(*Cache)
+
+func CacheMaxEntries(n int) CacheOption {
+	return func(c *Cache) {
+		c.maxEntries = n
 	}
 }
 
-func (c *Cache) keyFor(funcName string, args []any) (cacheKey, bool) {
+func CacheMaxBytes(n int64) CacheOption {
+	return func(c *Cache) {
+		c.maxBytes = n
+	}
+}
+
+func CacheSweepInterval(d time.Duration) CacheOption {
+	return func(c *Cache) {
+		c.sweepInterval = d
+	}
+}
+
+func NewCache(opts ...CacheOption) *Cache {
+	c := &Cache{
+		data: make(map[cacheKey]*list.Element),
+		lru:  list.New(),
+		stop: make(chan struct{}),
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	if c.sweepInterval > 0 {
+		go c.sweepEvery(c.sweepInterval)
+	}
+	return c
+}
+
+func (c *Cache) keyFor(info PluginInfo, funcName string, args []any) (cacheKey, bool) {
+	if containsHandle(args) {
+		return cacheKey{}, false
+	}
 	b, err := json.Marshal(args)
 	if err != nil {
 		return cacheKey{}, false
 	}
-	return cacheKey{funcName: funcName, args: string(b)}, true
+	return cacheKey{plugin: info.Name, version: info.Version, funcName: funcName, args: string(b)}, true
 }
 
-func (c *Cache) get(funcName string, args []any) (any, bool) {
-	k, ok := c.keyFor(funcName, args)
+func (c *Cache) get(info PluginInfo, funcName string, args []any) (any, bool) {
+	k, ok := c.keyFor(info, funcName, args)
 	if !ok {
 		return nil, false
 	}
 	c.mu.Lock()
 	defer c.mu.Unlock()
-	entry, ok := c.data[k]
+	e, ok := c.data[k]
 	if !ok {
+		c.stats.Misses++
 		return nil, false
 	}
+	entry := e.Value.(*cacheEntry)
 	if time.Now().After(entry.expiresAt) {
-		delete(c.data, k)
+		c.remove(e)
+		c.stats.Expirations++
+		c.stats.Misses++
 		return nil, false
 	}
+	c.lru.MoveToFront(e)
+	c.stats.Hits++
 	return entry.value, true
 }
 
-func (c *Cache) store(funcName string, args []any, value any, ttl time.Duration) {
-	if ttl == 0 {
+func (c *Cache) store(info PluginInfo, funcName string, args []any, value any, ttl time.Duration) {
+	if ttl == 0 || containsHandle(value) {
 		return
 	}
-	k, ok := c.keyFor(funcName, args)
+	k, ok := c.keyFor(info, funcName, args)
 	if !ok {
 		return
 	}
+	b, err := json.Marshal(value)
+	if err != nil {
+		return
+	}
+	size := int64(len(k.plugin) + len(k.version) + len(k.funcName) + len(k.args) + len(b))
+	if c.maxBytes > 0 && size > c.maxBytes {
+		return
+	}
 	c.mu.Lock()
 	defer c.mu.Unlock()
-	c.data[k] = cacheEntry{value: value, expiresAt: time.Now().Add(ttl)}
+	if e, ok := c.data[k]; ok {
+		c.remove(e)
+	}
+	c.data[k] = c.lru.PushFront(&cacheEntry{key: k, value: value, size: size, expiresAt: time.Now().Add(ttl)})
+	c.size += size
+	for c.full() {
+		c.remove(c.lru.Back())
+		c.stats.Evictions++
+	}
 }
 
-func (c *Cache) Writer(ttl func(funcName string, args []any) time.Duration) Middleware {
-	return HookMiddleware(func(next Invoker, funcName string, args []any) (any, error) {
-		result, err := next.Invoke(funcName, args)
-		if err == nil {
-			c.store(funcName, args, result, ttl(funcName, args))
+func (c *Cache) full() bool {
+	return (c.maxEntries > 0 && c.lru.Len() > c.maxEntries) || (c.maxBytes > 0 && c.size > c.maxBytes)
+}
+
+func (c *Cache) remove(e *list.Element) {
+	entry := c.lru.Remove(e).(*cacheEntry)
+	delete(c.data, entry.key)
+	c.size -= entry.size
+}
+
+func (c *Cache) sweepEvery(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			c.sweep()
+		case <-c.stop:
+			return
 		}
-		return result, err
-	})
+	}
 }
 
-func (c *Cache) Reader() Middleware {
-	return HookMiddleware(func(next Invoker, funcName string, args []any) (any, error) {
-		if v, ok := c.get(funcName, args); ok {
-			return v, nil
+func (c *Cache) sweep() {
+	now := time.Now()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for e := c.lru.Back(); e != nil; {
+		prev := e.Prev()
+		if now.After(e.Value.(*cacheEntry).expiresAt) {
+			c.remove(e)
+			c.stats.Expirations++
 		}
-		return next.Invoke(funcName, args)
+		e = prev
+	}
+}
+
+func (c *Cache) Stats() CacheStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	stats := c.stats
+	stats.Entries = c.lru.Len()
+	stats.Bytes = c.size
+	return stats
+}
+
+func (c *Cache) Close() error {
+	c.closeOnce.Do(func() {
+		close(c.stop)
 	})
+	return nil
+}
+
+func (c *Cache) Writer(ttl func(funcName string, args []any) time.Duration) PluginMiddleware {
+	return func(info PluginInfo) Middleware {
+		return HookMiddleware(func(next Invoker, funcName string, args []any) (any, error) {
+			result, err := next.Invoke(funcName, args)
+			if err == nil {
+				c.store(info, funcName, args, result, ttl(funcName, args))
+			}
+			return result, err
+		})
+	}
+}
+
+func (c *Cache) HintedWriter(maxTTL time.Duration) PluginMiddleware {
+	return func(info PluginInfo) Middleware {
+		return c.Writer(info.CacheTTL(maxTTL))(info)
+	}
+}
+
+func (c *Cache) Reader() PluginMiddleware {
+	return func(info PluginInfo) Middleware {
+		return HookMiddleware(func(next Invoker, funcName string, args []any) (any, error) {
+			if v, ok := c.get(info, funcName, args); ok {
+				return v, nil
+			}
+			return next.Invoke(funcName, args)
+		})
+	}
 }
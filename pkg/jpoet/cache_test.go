@@ -0,0 +1,163 @@
+package jpoet
+
+import (
+	"testing"
+	"time"
+
+	"github.com/marcbran/jpoet/internal/plugin"
+)
+
+func cacheInvoker(cache *Cache, next Invoker, ttl time.Duration) Invoker {
+	writer := cache.Writer(func(string, []any) time.Duration {
+		return ttl
+	})
+	return cache.Reader()(PluginInfo{})(writer(PluginInfo{})(next))
+}
+
+func TestCache_MaxEntries(t *testing.T) {
+	cache := NewCache(CacheMaxEntries(2))
+	next := &countingInvoker{}
+	invoker := cacheInvoker(cache, next, time.Hour)
+
+	for _, arg := range []string{"a", "b", "a", "c", "a", "b"} {
+		_, err := invoker.Invoke("upper", []any{arg})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if next.calls != 4 {
+		t.Errorf("expected 4 calls, got %d", next.calls)
+	}
+	stats := cache.Stats()
+	if stats.Hits != 2 || stats.Misses != 4 || stats.Evictions != 2 || stats.Entries != 2 {
+		t.Errorf("unexpected stats: %+v", stats)
+	}
+}
+
+func TestCache_MaxBytes(t *testing.T) {
+	cache := NewCache(CacheMaxBytes(100))
+	invoker := cacheInvoker(cache, &countingInvoker{}, time.Hour)
+
+	for _, arg := range []string{"a", "b", "c"} {
+		_, err := invoker.Invoke("upper", []any{arg})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	stats := cache.Stats()
+	if stats.Bytes > 100 || stats.Evictions == 0 {
+		t.Errorf("expected entries to be evicted to stay within 100 bytes, got %+v", stats)
+	}
+}
+
+func TestCache_Sweep(t *testing.T) {
+	cache := NewCache(CacheSweepInterval(time.Millisecond))
+	defer func() {
+		_ = cache.Close()
+	}()
+	invoker := cacheInvoker(cache, &countingInvoker{}, time.Millisecond)
+
+	_, err := invoker.Invoke("upper", []any{"a"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	deadline := time.Now().Add(time.Second)
+	for cache.Stats().Entries > 0 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	stats := cache.Stats()
+	if stats.Entries != 0 || stats.Expirations != 1 {
+		t.Errorf("expected expired entry to be swept, got %+v", stats)
+	}
+}
+
+func TestCache_SharedByPlugins(t *testing.T) {
+	cache := NewCache()
+	a := &countingInvoker{}
+	b := &countingInvoker{}
+	pa := (&Plugin{name: "a", invoker: a}).WithCacheHints(map[string]CacheHint{"get": {Pure: true}}).WithPluginMiddleware(cache.HintedWriter(time.Hour), cache.Reader())
+	pb := (&Plugin{name: "b", invoker: b}).WithCacheHints(map[string]CacheHint{"get": {Pure: true}}).WithPluginMiddleware(cache.HintedWriter(time.Hour), cache.Reader())
+
+	var out []float64
+	err := Eval(
+		SnippetInput("main.jsonnet", `
+			local a = std.native('invoke:a');
+			local b = std.native('invoke:b');
+			[a('get', ['x']).calls, b('get', ['x']).calls, a('get', ['x']).calls, b('get', ['x']).calls]
+		`),
+		WithPluginFunctions(pa, pb),
+		Serialize(false),
+		ValueOutput(&out),
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if a.calls != 1 || b.calls != 1 {
+		t.Errorf("expected each plugin to be invoked once, got %d and %d calls", a.calls, b.calls)
+	}
+	if len(out) != 4 || out[0] != 1 || out[1] != 1 || out[2] != 1 || out[3] != 1 {
+		t.Errorf("unexpected results: %v", out)
+	}
+}
+
+func TestCache_HintedWriter(t *testing.T) {
+	cache := NewCache()
+	next := &countingInvoker{}
+	p := (&Plugin{name: "test", invoker: next}).WithCacheHints(map[string]CacheHint{
+		"pure":  {Pure: true},
+		"never": {Never: true, TTL: time.Hour},
+	})
+	info := PluginInfo{Name: "test", plugin: p}
+	invoker := cache.Reader()(info)(cache.HintedWriter(time.Hour)(info)(next))
+
+	for _, funcName := range []string{"pure", "pure", "never", "never", "unhinted", "unhinted"} {
+		_, err := invoker.Invoke(funcName, []any{})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if next.calls != 5 {
+		t.Errorf("expected only the pure function to be cached, got %d calls", next.calls)
+	}
+}
+
+type handleInvoker struct {
+	calls int
+}
+
+func (h *handleInvoker) Invoke(funcName string, args []any) (any, error) {
+	h.calls++
+	return map[string]any{plugin.HandleKey: "1", plugin.HandlePluginKey: "test"}, nil
+}
+
+func TestCache_Handle(t *testing.T) {
+	cache := NewCache()
+	next := &countingInvoker{}
+	invoker := cacheInvoker(cache, next, time.Hour)
+
+	args := []any{map[string]any{plugin.HandleKey: "1", plugin.HandlePluginKey: "test"}}
+	for range 2 {
+		_, err := invoker.Invoke("read", args)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if next.calls != 2 {
+		t.Errorf("expected calls with handles not to be cached, got %d calls", next.calls)
+	}
+
+	opener := &handleInvoker{}
+	invoker = cacheInvoker(cache, opener, time.Hour)
+	for range 2 {
+		_, err := invoker.Invoke("open", []any{"a"})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if opener.calls != 2 {
+		t.Errorf("expected results with handles not to be cached, got %d calls", opener.calls)
+	}
+	if cache.Stats().Entries != 0 {
+		t.Errorf("expected no entries, got %+v", cache.Stats())
+	}
+}
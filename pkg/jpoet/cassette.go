@@ -0,0 +1,154 @@
+package jpoet
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+)
+
+type Cassette struct {
+	mu           sync.Mutex
+	interactions []Interaction
+	recorded     map[interactionKey][]Interaction
+	replayed     map[interactionKey]int
+}
+
+type Interaction struct {
+	Plugin    string          `json:"plugin"`
+	FuncName  string          `json:"funcName"`
+	Args      json.RawMessage `json:"args"`
+	Value     any             `json:"value,omitempty"`
+	Error     string          `json:"error,omitempty"`
+	Retryable bool            `json:"retryable,omitempty"`
+}
+
+type interactionKey struct {
+	plugin   string
+	funcName string
+	args     string
+}
+
+type cassetteFile struct {
+	Interactions []Interaction `json:"interactions"`
+}
+
+func NewCassette() *Cassette {
+	return &Cassette{
+		recorded: make(map[interactionKey][]Interaction),
+		replayed: make(map[interactionKey]int),
+	}
+}
+
+func LoadCassette(path string) (*Cassette, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read cassette: %w", err)
+	}
+	var f cassetteFile
+	err = json.Unmarshal(b, &f)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse cassette %s: %w", path, err)
+	}
+	c := NewCassette()
+	c.interactions = f.Interactions
+	for _, interaction := range f.Interactions {
+		var args []any
+		err = json.Unmarshal(interaction.Args, &args)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse cassette %s: invalid arguments of %s.%s: %w", path, interaction.Plugin, interaction.FuncName, err)
+		}
+		k, err := newInteractionKey(interaction.Plugin, interaction.FuncName, args)
+		if err != nil {
+			return nil, err
+		}
+		c.recorded[k] = append(c.recorded[k], interaction)
+	}
+	return c, nil
+}
+
+func (c *Cassette) Save(path string) error {
+	c.mu.Lock()
+	f := cassetteFile{Interactions: c.interactions}
+	if f.Interactions == nil {
+		f.Interactions = []Interaction{}
+	}
+	b, err := json.MarshalIndent(f, "", "  ")
+	c.mu.Unlock()
+	if err != nil {
+		return fmt.Errorf("failed to encode cassette: %w", err)
+	}
+	err = writeFileAtomic(path, append(b, '\n'))
+	if err != nil {
+		return fmt.Errorf("failed to write cassette: %w", err)
+	}
+	return nil
+}
+
+func (c *Cassette) Recorder() PluginMiddleware {
+	return func(info PluginInfo) Middleware {
+		return HookMiddleware(func(next Invoker, funcName string, args []any) (any, error) {
+			result, err := next.Invoke(funcName, args)
+			c.record(info.Name, funcName, args, result, err)
+			return result, err
+		})
+	}
+}
+
+func (c *Cassette) record(pluginName string, funcName string, args []any, value any, err error) {
+	b, merr := json.Marshal(args)
+	if merr != nil {
+		return
+	}
+	interaction := Interaction{Plugin: pluginName, FuncName: funcName, Args: b}
+	if err != nil {
+		interaction.Error = err.Error()
+		interaction.Retryable = IsRetryable(err)
+	} else {
+		interaction.Value = value
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.interactions = append(c.interactions, interaction)
+}
+
+func (c *Cassette) Replayer() PluginMiddleware {
+	return func(info PluginInfo) Middleware {
+		return HookMiddleware(func(next Invoker, funcName string, args []any) (any, error) {
+			return c.replay(info.Name, funcName, args)
+		})
+	}
+}
+
+func newInteractionKey(pluginName string, funcName string, args []any) (interactionKey, error) {
+	b, err := json.Marshal(args)
+	if err != nil {
+		return interactionKey{}, fmt.Errorf("failed to encode arguments of %s.%s: %w", pluginName, funcName, err)
+	}
+	return interactionKey{plugin: pluginName, funcName: funcName, args: string(b)}, nil
+}
+
+func (c *Cassette) replay(pluginName string, funcName string, args []any) (any, error) {
+	k, err := newInteractionKey(pluginName, funcName, args)
+	if err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	matches := c.recorded[k]
+	if len(matches) == 0 {
+		return nil, fmt.Errorf("unexpected invocation of %s.%s with arguments %s, which is not recorded in the cassette", pluginName, funcName, k.args)
+	}
+	n := c.replayed[k]
+	c.replayed[k] = n + 1
+	interaction := matches[min(n, len(matches)-1)]
+	if interaction.Error != "" {
+		err := errors.New(interaction.Error)
+		if interaction.Retryable {
+			return nil, Retryable(err)
+		}
+		return nil, err
+	}
+	return interaction.Value, nil
+}
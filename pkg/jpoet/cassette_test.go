@@ -0,0 +1,110 @@
+package jpoet
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func recordCassette(t *testing.T, next Invoker, calls func(invoker Invoker)) string {
+	t.Helper()
+	cassette := NewCassette()
+	calls(cassette.Recorder()(PluginInfo{Name: "test"})(next))
+	path := filepath.Join(t.TempDir(), "cassette.json")
+	err := cassette.Save(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return path
+}
+
+// replayCassette replays the cassette in front of a plugin that must not be invoked.
+func replayCassette(t *testing.T, path string) Invoker {
+	t.Helper()
+	cassette, err := LoadCassette(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	next := &countingInvoker{}
+	t.Cleanup(func() {
+		if next.calls != 0 {
+			t.Errorf("expected the plugin not to be invoked, got %d calls", next.calls)
+		}
+	})
+	return cassette.Replayer()(PluginInfo{Name: "test"})(next)
+}
+
+func TestCassette_Replay(t *testing.T) {
+	path := recordCassette(t, &countingInvoker{}, func(invoker Invoker) {
+		_, _ = invoker.Invoke("upper", []any{"a"})
+		_, _ = invoker.Invoke("upper", []any{"b"})
+		_, _ = invoker.Invoke("upper", []any{"a"})
+	})
+	invoker := replayCassette(t, path)
+
+	for _, expected := range []float64{1, 3, 3} {
+		res, err := invoker.Invoke("upper", []any{"a"})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if !reflect.DeepEqual(res, map[string]any{"funcName": "upper", "calls": expected}) {
+			t.Errorf("expected call %v, got %v", expected, res)
+		}
+	}
+	res, err := invoker.Invoke("upper", []any{"b"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(res, map[string]any{"funcName": "upper", "calls": 2.0}) {
+		t.Errorf("expected call 2, got %v", res)
+	}
+}
+
+func TestCassette_ReplayUnexpected(t *testing.T) {
+	path := recordCassette(t, &countingInvoker{}, func(invoker Invoker) {
+		_, _ = invoker.Invoke("upper", []any{"a"})
+	})
+	invoker := replayCassette(t, path)
+
+	_, err := invoker.Invoke("upper", []any{"c"})
+	if err == nil || !strings.Contains(err.Error(), `unexpected invocation of test.upper with arguments ["c"]`) {
+		t.Errorf("expected unexpected invocation error, got: %v", err)
+	}
+	_, err = invoker.Invoke("lower", []any{"a"})
+	if err == nil || !strings.Contains(err.Error(), "unexpected invocation of test.lower") {
+		t.Errorf("expected unexpected invocation error, got: %v", err)
+	}
+}
+
+func TestCassette_ReplayError(t *testing.T) {
+	next := &failingInvoker{failures: 1, err: Retryable(errors.New("busy"))}
+	path := recordCassette(t, next, func(invoker Invoker) {
+		_, _ = invoker.Invoke("fetch", []any{})
+	})
+	invoker := replayCassette(t, path)
+
+	_, err := invoker.Invoke("fetch", []any{})
+	if err == nil || err.Error() != "busy" || !IsRetryable(err) {
+		t.Errorf("expected retryable busy error, got: %v", err)
+	}
+}
+
+func TestCassette_ReplayFormatted(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cassette.json")
+	err := os.WriteFile(path, []byte(`{"interactions": [{"plugin": "test", "funcName": "upper", "args": [ "a" ], "value": "A"}]}`), 0o644)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	invoker := replayCassette(t, path)
+
+	res, err := invoker.Invoke("upper", []any{"a"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if res != "A" {
+		t.Errorf("expected A, got %v", res)
+	}
+}
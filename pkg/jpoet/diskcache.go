@@ -0,0 +1,241 @@
+package jpoet
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/marcbran/jpoet/internal/plugin"
+)
+
+type DiskCache struct {
+	dir string
+}
+
+func NewDiskCache(dir string) *DiskCache {
+	return &DiskCache{dir: dir}
+}
+
+func DefaultCacheDir() (string, error) {
+	cacheDir, err := os.UserCacheDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to find the cache directory: %w", err)
+	}
+	return filepath.Join(cacheDir, "jpoet", "results"), nil
+}
+
+type diskCacheEntry struct {
+	Plugin    string          `json:"plugin"`
+	Version   string          `json:"version"`
+	FuncName  string          `json:"funcName"`
+	Args      json.RawMessage `json:"args"`
+	ExpiresAt time.Time       `json:"expiresAt"`
+	Value     any             `json:"value"`
+}
+
+const diskCacheTemp = ".tmp-"
+
+func (c *DiskCache) path(info PluginInfo, funcName string, args []any) (string, []byte, bool) {
+	if info.Version == "" || containsHandle(args) {
+		return "", nil, false
+	}
+	b, err := json.Marshal(args)
+	if err != nil {
+		return "", nil, false
+	}
+	key, err := json.Marshal([]any{info.Name, info.Version, funcName, json.RawMessage(b)})
+	if err != nil {
+		return "", nil, false
+	}
+	sum := sha256.Sum256(key)
+	name := hex.EncodeToString(sum[:])
+	return filepath.Join(c.dir, name[:2], name+".json"), b, true
+}
+
+func (c *DiskCache) get(info PluginInfo, funcName string, args []any) (any, bool) {
+	path, b, ok := c.path(info, funcName, args)
+	if !ok {
+		return nil, false
+	}
+	entry, err := readDiskCacheEntry(path)
+	if err != nil {
+		return nil, false
+	}
+	if entry.Plugin != info.Name || entry.Version != info.Version || entry.FuncName != funcName || string(entry.Args) != string(b) {
+		return nil, false
+	}
+	if time.Now().After(entry.ExpiresAt) {
+		_ = os.Remove(path)
+		return nil, false
+	}
+	return entry.Value, true
+}
+
+func (c *DiskCache) store(info PluginInfo, funcName string, args []any, value any, ttl time.Duration) {
+	if ttl == 0 || containsHandle(value) {
+		return
+	}
+	path, b, ok := c.path(info, funcName, args)
+	if !ok {
+		return
+	}
+	entry, err := json.Marshal(diskCacheEntry{
+		Plugin:    info.Name,
+		Version:   info.Version,
+		FuncName:  funcName,
+		Args:      b,
+		ExpiresAt: time.Now().Add(ttl),
+		Value:     value,
+	})
+	if err != nil {
+		return
+	}
+	_ = writeFileAtomic(path, entry)
+}
+
+func readDiskCacheEntry(path string) (diskCacheEntry, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return diskCacheEntry{}, err
+	}
+	var entry diskCacheEntry
+	err = json.Unmarshal(b, &entry)
+	if err != nil {
+		return diskCacheEntry{}, err
+	}
+	return entry, nil
+}
+
+func writeFileAtomic(path string, b []byte) error {
+	dir := filepath.Dir(path)
+	err := os.MkdirAll(dir, 0o755)
+	if err != nil {
+		return err
+	}
+	f, err := os.CreateTemp(dir, diskCacheTemp+"*")
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(b)
+	closeErr := f.Close()
+	if err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(f.Name(), path)
+	}
+	if err != nil {
+		_ = os.Remove(f.Name())
+	}
+	return err
+}
+
+func containsHandle(v any) bool {
+	if _, ok := plugin.HandleID(v); ok {
+		return true
+	}
+	switch v := v.(type) {
+	case []any:
+		for _, e := range v {
+			if containsHandle(e) {
+				return true
+			}
+		}
+	case map[string]any:
+		for _, e := range v {
+			if containsHandle(e) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (c *DiskCache) Writer(ttl func(funcName string, args []any) time.Duration) PluginMiddleware {
+	return func(info PluginInfo) Middleware {
+		return HookMiddleware(func(next Invoker, funcName string, args []any) (any, error) {
+			result, err := next.Invoke(funcName, args)
+			if err == nil {
+				c.store(info, funcName, args, result, ttl(funcName, args))
+			}
+			return result, err
+		})
+	}
+}
+
+func (c *DiskCache) HintedWriter(maxTTL time.Duration) PluginMiddleware {
+	return func(info PluginInfo) Middleware {
+		return c.Writer(info.CacheTTL(maxTTL))(info)
+	}
+}
+
+func (c *DiskCache) Reader() PluginMiddleware {
+	return func(info PluginInfo) Middleware {
+		return HookMiddleware(func(next Invoker, funcName string, args []any) (any, error) {
+			if v, ok := c.get(info, funcName, args); ok {
+				return v, nil
+			}
+			return next.Invoke(funcName, args)
+		})
+	}
+}
+
+type DiskCacheStats struct {
+	Entries int
+	Expired int
+	Size    int64
+}
+
+func (c *DiskCache) Stats() (DiskCacheStats, error) {
+	var stats DiskCacheStats
+	now := time.Now()
+	err := c.walk(func(path string, d fs.DirEntry) error {
+		info, err := d.Info()
+		if err != nil {
+			return nil
+		}
+		entry, err := readDiskCacheEntry(path)
+		if err != nil {
+			return nil
+		}
+		stats.Entries++
+		stats.Size += info.Size()
+		if now.After(entry.ExpiresAt) {
+			stats.Expired++
+		}
+		return nil
+	})
+	return stats, err
+}
+
+func (c *DiskCache) Clear() error {
+	return c.walk(func(path string, _ fs.DirEntry) error {
+		err := os.Remove(path)
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+		return nil
+	})
+}
+
+func (c *DiskCache) walk(f func(path string, d fs.DirEntry) error) error {
+	return filepath.WalkDir(c.dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				return nil
+			}
+			return err
+		}
+		if d.IsDir() || strings.HasPrefix(d.Name(), diskCacheTemp) || filepath.Ext(d.Name()) != ".json" {
+			return nil
+		}
+		return f(path, d)
+	})
+}
@@ -0,0 +1,134 @@
+package jpoet
+
+import (
+	"testing"
+	"time"
+
+	"github.com/marcbran/jpoet/internal/plugin"
+)
+
+type countingInvoker struct {
+	calls int
+}
+
+func (c *countingInvoker) Invoke(funcName string, args []any) (any, error) {
+	c.calls++
+	return map[string]any{"funcName": funcName, "calls": float64(c.calls)}, nil
+}
+
+func diskCacheInvoker(cache *DiskCache, info PluginInfo, next Invoker, ttl time.Duration) Invoker {
+	writer := cache.Writer(func(string, []any) time.Duration {
+		return ttl
+	})
+	return cache.Reader()(info)(writer(info)(next))
+}
+
+func TestDiskCache_Hit(t *testing.T) {
+	dir := t.TempDir()
+	next := &countingInvoker{}
+	info := PluginInfo{Name: "test", Version: "v1.0.0"}
+
+	_, err := diskCacheInvoker(NewDiskCache(dir), info, next, time.Hour).Invoke("upper", []any{"a"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	res, err := diskCacheInvoker(NewDiskCache(dir), info, next, time.Hour).Invoke("upper", []any{"a"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if next.calls != 1 {
+		t.Errorf("expected 1 call, got %d", next.calls)
+	}
+	if res.(map[string]any)["calls"] != float64(1) {
+		t.Errorf("expected cached result, got %v", res)
+	}
+
+	_, err = diskCacheInvoker(NewDiskCache(dir), PluginInfo{Name: "test", Version: "v2.0.0"}, next, time.Hour).Invoke("upper", []any{"a"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if next.calls != 2 {
+		t.Errorf("expected other plugin version to miss the cache, got %d calls", next.calls)
+	}
+}
+
+func TestDiskCache_Expired(t *testing.T) {
+	cache := NewDiskCache(t.TempDir())
+	next := &countingInvoker{}
+	invoker := diskCacheInvoker(cache, PluginInfo{Name: "test", Version: "v1.0.0"}, next, time.Nanosecond)
+
+	for range 2 {
+		_, err := invoker.Invoke("upper", []any{"a"})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if next.calls != 2 {
+		t.Errorf("expected expired entry to be invoked again, got %d calls", next.calls)
+	}
+}
+
+func TestDiskCache_Unversioned(t *testing.T) {
+	cache := NewDiskCache(t.TempDir())
+	next := &countingInvoker{}
+	invoker := diskCacheInvoker(cache, PluginInfo{Name: "test"}, next, time.Hour)
+
+	for range 2 {
+		_, err := invoker.Invoke("upper", []any{"a"})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if next.calls != 2 {
+		t.Errorf("expected calls of plugins without version not to be cached, got %d calls", next.calls)
+	}
+}
+
+func TestDiskCache_Handle(t *testing.T) {
+	cache := NewDiskCache(t.TempDir())
+	next := &countingInvoker{}
+	invoker := diskCacheInvoker(cache, PluginInfo{Name: "test", Version: "v1.0.0"}, next, time.Hour)
+
+	args := []any{map[string]any{plugin.HandleKey: "1", plugin.HandlePluginKey: "test"}}
+	for range 2 {
+		_, err := invoker.Invoke("read", args)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if next.calls != 2 {
+		t.Errorf("expected calls with handles not to be cached, got %d calls", next.calls)
+	}
+}
+
+func TestDiskCache_StatsAndClear(t *testing.T) {
+	cache := NewDiskCache(t.TempDir())
+	invoker := diskCacheInvoker(cache, PluginInfo{Name: "test", Version: "v1.0.0"}, &countingInvoker{}, time.Hour)
+	for _, arg := range []string{"a", "b"} {
+		_, err := invoker.Invoke("upper", []any{arg})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	stats, err := cache.Stats()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if stats.Entries != 2 || stats.Expired != 0 || stats.Size == 0 {
+		t.Errorf("unexpected stats: %+v", stats)
+	}
+
+	err = cache.Clear()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	stats, err = cache.Stats()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if stats.Entries != 0 {
+		t.Errorf("expected empty cache, got %+v", stats)
+	}
+}
@@ -18,7 +18,11 @@ type Option func(*evalConfig)
 
 type evalConfig struct {
 	vmOpts  []func(*jsonnet.VM)
+	natives []*jsonnet.NativeFunction
 	closers []io.Closer
+	host    *evalHost
+	handles *handleTracker
+	plugins map[string]bool
 
 	importer CompoundImporter
 	contents map[string]jsonnet.Contents
@@ -33,6 +37,8 @@ type evalConfig struct {
 
 	serializedFormat bool
 
+	tracer *Tracer
+
 	errs []error
 }
 
@@ -124,14 +130,32 @@ func WithNativeFunction(f *jsonnet.NativeFunction) Option {
 		if f == nil {
 			return
 		}
-		c.vmOpts = append(c.vmOpts, func(vm *jsonnet.VM) { vm.NativeFunction(f) })
+		c.natives = append(c.natives, f)
 	}
 }
 
 func WithPlugin(p *Plugin) Option {
 	return func(c *evalConfig) {
 		c.closers = append(c.closers, p)
-		WithNativeFunction(p.NativeFunction())(c)
+		WithPluginFunctions(p)(c)
+	}
+}
+
+func WithPluginFunctions(plugins ...*Plugin) Option {
+	return func(c *evalConfig) {
+		if c.host == nil {
+			c.host = &evalHost{config: c}
+			c.handles = newHandleTracker()
+			c.plugins = make(map[string]bool)
+		}
+		for _, p := range plugins {
+			if c.plugins[p.name] {
+				c.errs = append(c.errs, fmt.Errorf("more than one plugin provides invoke:%s, give one of them an alias", p.name))
+				continue
+			}
+			c.plugins[p.name] = true
+			c.natives = append(c.natives, p.nativeFunctions(c.host, c.handles)...)
+		}
 	}
 }
 
@@ -173,6 +197,19 @@ func Serialize(s bool) Option {
 	}
 }
 
+func WithTracer(t *Tracer) Option {
+	return func(c *evalConfig) {
+		c.tracer = t
+	}
+}
+
+func (c *evalConfig) span(name string) func() {
+	if c.tracer == nil {
+		return func() {}
+	}
+	return c.tracer.span("eval", name, nil)
+}
+
 func (c *evalConfig) hasInput() bool {
 	return c.nodeInput != nil || c.snippetInput != nil || c.fileInput != nil
 }
@@ -189,6 +226,12 @@ func (c *evalConfig) error() error {
 
 func (c *evalConfig) eval() error {
 	defer func() {
+		if c.handles != nil {
+			err := c.handles.releaseAll()
+			if err != nil {
+				c.errs = append(c.errs, err)
+			}
+		}
 		for _, closer := range c.closers {
 			err := closer.Close()
 			if err != nil {
@@ -200,54 +243,85 @@ func (c *evalConfig) eval() error {
 		c.errs = append(c.errs, errors.New("missing input"))
 		return c.error()
 	}
+	if len(c.errs) > 0 {
+		return c.error()
+	}
 	if len(c.contents) > 0 {
 		c.importer.Importers = append(c.importer.Importers, &MemoryImporter{
 			Data: c.contents,
 		})
 	}
+	end := c.span("setup")
+	vm := c.makeVM()
+	end()
+
+	end = c.span("evaluate")
+	serializedJson, err := c.evaluate(vm)
+	end()
+	if err != nil {
+		c.errs = append(c.errs, err)
+		return c.error()
+	}
+
+	end = c.span("output")
+	err = c.output(serializedJson)
+	end()
+	if err != nil {
+		c.errs = append(c.errs, err)
+		return c.error()
+	}
+	return c.error()
+}
+
+func (c *evalConfig) makeVM() *jsonnet.VM {
 	vm := jsonnet.MakeVM()
 	for _, opt := range c.vmOpts {
 		opt(vm)
 	}
+	for _, f := range c.natives {
+		vm.NativeFunction(f)
+	}
+	var importer jsonnet.Importer
 	if len(c.importer.Importers) > 0 {
-		vm.Importer(c.importer)
+		importer = c.importer
 	}
-
-	var serializedJson string
-	var err error
-	if c.nodeInput != nil {
-		serializedJson, err = vm.Evaluate(*c.nodeInput)
-		if err != nil {
-			c.errs = append(c.errs, err)
-			return c.error()
-		}
-	} else if c.snippetInput != nil {
-		serializedJson, err = vm.EvaluateAnonymousSnippet(c.snippetInput.filename, c.snippetInput.snippet)
-		if err != nil {
-			c.errs = append(c.errs, err)
-			return c.error()
-		}
-	} else if c.fileInput != nil {
-		serializedJson, err = vm.EvaluateFile(*c.fileInput)
-		if err != nil {
-			c.errs = append(c.errs, err)
-			return c.error()
+	if c.tracer != nil {
+		if importer == nil {
+			importer = &jsonnet.FileImporter{}
 		}
+		importer = tracingImporter{tracer: c.tracer, importer: importer}
+	}
+	if importer != nil {
+		vm.Importer(importer)
 	}
+	return vm
+}
+
+func (c *evalConfig) evaluate(vm *jsonnet.VM) (string, error) {
+	switch {
+	case c.nodeInput != nil:
+		return vm.Evaluate(*c.nodeInput)
+	case c.snippetInput != nil:
+		return vm.EvaluateAnonymousSnippet(c.snippetInput.filename, c.snippetInput.snippet)
+	case c.fileInput != nil:
+		return vm.EvaluateFile(*c.fileInput)
+	default:
+		return "", nil
+	}
+}
 
+func (c *evalConfig) output(serializedJson string) error {
 	if c.writerOutput != nil {
 		output := serializedJson
 		if !c.serializedFormat {
 			err := json.Unmarshal([]byte(serializedJson), &output)
 			if err != nil {
-				c.errs = append(c.errs, err)
-				return c.error()
+				return err
 			}
 		}
 		_, err := c.writerOutput.Write([]byte(output))
 		if err != nil {
-			c.errs = append(c.errs, err)
-			return c.error()
+			return err
 		}
 	} else if c.valueOutput != nil {
 		if c.serializedFormat {
@@ -255,24 +329,21 @@ func (c *evalConfig) eval() error {
 		} else {
 			err := json.Unmarshal([]byte(serializedJson), c.valueOutput)
 			if err != nil {
-				c.errs = append(c.errs, err)
-				return c.error()
+				return err
 			}
 		}
 	} else if c.directoryOutput != "" {
 		var entries map[string]any
-		err = json.Unmarshal([]byte(serializedJson), &entries)
+		err := json.Unmarshal([]byte(serializedJson), &entries)
 		if err != nil {
-			c.errs = append(c.errs, err)
-			return c.error()
+			return err
 		}
 		err = writeEntries(c.directoryOutput, entries, c.serializedFormat)
 		if err != nil {
-			c.errs = append(c.errs, err)
-			return c.error()
+			return err
 		}
 	}
-	return c.error()
+	return nil
 }
 
 func writeEntries(directory string, entries map[string]any, serialized bool) error {
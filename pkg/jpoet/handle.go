@@ -0,0 +1,107 @@
+package jpoet
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"sync"
+
+	"github.com/marcbran/jpoet/internal/plugin"
+)
+
+type Handle = plugin.Handle
+
+func NewHandle(value any) *Handle {
+	return plugin.NewHandle(value)
+}
+
+type handleTracker struct {
+	mu      sync.Mutex
+	plugins map[string]*trackedHandles
+}
+
+type trackedHandles struct {
+	invoker    plugin.Invoker
+	generation uint64
+	ids        map[string]bool
+}
+
+func newHandleTracker() *handleTracker {
+	return &handleTracker{
+		plugins: make(map[string]*trackedHandles),
+	}
+}
+
+func (t *handleTracker) check(pluginName string, args []any) error {
+	for _, arg := range args {
+		id, ok := plugin.HandleID(arg)
+		if !ok {
+			continue
+		}
+		owner, _ := arg.(map[string]any)[plugin.HandlePluginKey].(string)
+		if owner != pluginName {
+			return fmt.Errorf("handle %s of plugin %s cannot be passed to plugin %s", id, owner, pluginName)
+		}
+		t.mu.Lock()
+		tracked := t.plugins[pluginName]
+		live := tracked != nil && tracked.ids[id]
+		restarted := tracked != nil && tracked.restarted()
+		t.mu.Unlock()
+		if restarted && live {
+			return fmt.Errorf("handle %s of plugin %s was lost when the plugin was restarted", id, pluginName)
+		}
+		if !live {
+			return fmt.Errorf("handle %s of plugin %s has been released or was not created during this evaluation", id, pluginName)
+		}
+	}
+	return nil
+}
+
+func (t *handleTracker) track(pluginName string, invoker plugin.Invoker, value any) any {
+	id, ok := plugin.HandleID(value)
+	if !ok {
+		return value
+	}
+	value.(map[string]any)[plugin.HandlePluginKey] = pluginName
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	tracked, ok := t.plugins[pluginName]
+	if !ok {
+		tracked = &trackedHandles{
+			invoker:    invoker,
+			generation: plugin.Generation(invoker),
+			ids:        make(map[string]bool),
+		}
+		t.plugins[pluginName] = tracked
+	}
+	tracked.restarted()
+	tracked.ids[id] = true
+	return value
+}
+
+func (t *trackedHandles) restarted() bool {
+	generation := plugin.Generation(t.invoker)
+	if generation == t.generation {
+		return false
+	}
+	t.generation = generation
+	clear(t.ids)
+	return true
+}
+
+func (t *handleTracker) releaseAll() error {
+	t.mu.Lock()
+	plugins := t.plugins
+	t.plugins = make(map[string]*trackedHandles)
+	t.mu.Unlock()
+	var errs []error
+	for _, name := range slices.Sorted(maps.Keys(plugins)) {
+		tracked := plugins[name]
+		err := plugin.Release(tracked.invoker, slices.Sorted(maps.Keys(tracked.ids)))
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to release handles of plugin %s: %w", name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,152 @@
+package jpoet
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/google/go-jsonnet"
+	"github.com/google/go-jsonnet/ast"
+	"github.com/marcbran/jpoet/internal/plugin"
+)
+
+type testResource struct {
+	name   string
+	closed bool
+}
+
+func (r *testResource) Close() error {
+	r.closed = true
+	return nil
+}
+
+func newHandleTestPlugin(name string, resources *[]*testResource) *Plugin {
+	return NewPlugin(name, []jsonnet.NativeFunction{
+		{
+			Name:   "open",
+			Params: ast.Identifiers{"name"},
+			Func: func(args []any) (any, error) {
+				r := &testResource{name: args[0].(string)}
+				*resources = append(*resources, r)
+				return NewHandle(r), nil
+			},
+		},
+		{
+			Name:   "name",
+			Params: ast.Identifiers{"resource"},
+			Func: func(args []any) (any, error) {
+				r, ok := args[0].(*testResource)
+				if !ok {
+					return nil, nil
+				}
+				return r.name, nil
+			},
+		},
+	})
+}
+
+func TestHandle_Lifecycle(t *testing.T) {
+	var resources []*testResource
+	p := newHandleTestPlugin("db", &resources)
+
+	var out []string
+	err := Eval(
+		SnippetInput("main.jsonnet", `
+			local db = std.native('invoke:db');
+			local a = db('open', ['a']);
+			local b = db('open', ['b']);
+			[db('name', [a]), db('name', [b]), db('name', [a])]
+		`),
+		WithPluginFunctions(p),
+		Serialize(false),
+		ValueOutput(&out),
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if strings.Join(out, ",") != "a,b,a" {
+		t.Errorf("unexpected result: %v", out)
+	}
+	if len(resources) != 2 || !resources[0].closed || !resources[1].closed {
+		t.Errorf("expected both resources to be opened once and closed after the evaluation")
+	}
+}
+
+func TestHandle_OtherPlugin(t *testing.T) {
+	var resources []*testResource
+	err := Eval(
+		SnippetInput("main.jsonnet", `
+			local a = std.native('invoke:a')('open', ['a']);
+			std.native('invoke:b')('name', [a])
+		`),
+		WithPluginFunctions(newHandleTestPlugin("a", &resources), newHandleTestPlugin("b", &resources)),
+		WriterOutput(&strings.Builder{}),
+	)
+	if err == nil || !strings.Contains(err.Error(), "of plugin a cannot be passed to plugin b") {
+		t.Errorf("expected other plugin error, got: %v", err)
+	}
+}
+
+func TestHandle_Released(t *testing.T) {
+	var resources []*testResource
+	p := newHandleTestPlugin("db", &resources)
+
+	var handle map[string]any
+	err := Eval(
+		SnippetInput("main.jsonnet", `std.native('invoke:db')('open', ['a'])`),
+		WithPluginFunctions(p),
+		Serialize(false),
+		ValueOutput(&handle),
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	id, ok := handle["$handle"].(string)
+	if !ok || handle["$plugin"] != "db" {
+		t.Fatalf("unexpected handle: %v", handle)
+	}
+	if !resources[0].closed {
+		t.Errorf("expected resource to be closed after the evaluation")
+	}
+
+	err = Eval(
+		SnippetInput("main.jsonnet", fmt.Sprintf(`std.native('invoke:db')('name', [{ "$handle": %q, "$plugin": "db" }])`, id)),
+		WithPluginFunctions(p),
+		WriterOutput(&strings.Builder{}),
+	)
+	if err == nil || !strings.Contains(err.Error(), "handle "+id+" of plugin db has been released or was not created during this evaluation") {
+		t.Errorf("expected released handle error, got: %v", err)
+	}
+}
+
+type restartingInvoker struct {
+	Invoker
+	generation uint64
+}
+
+func (i *restartingInvoker) Generation() uint64 {
+	return i.generation
+}
+
+func TestHandle_Restarted(t *testing.T) {
+	var resources []*testResource
+	invoker := &restartingInvoker{Invoker: newHandleTestPlugin("db", &resources).invoker, generation: 1}
+	handles := newHandleTracker()
+
+	handle := handles.track("db", invoker, map[string]any{plugin.HandleKey: "a-1"})
+	err := handles.check("db", []any{handle})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	invoker.generation++
+	err = handles.check("db", []any{handle})
+	if err == nil || !strings.Contains(err.Error(), "handle a-1 of plugin db was lost when the plugin was restarted") {
+		t.Errorf("expected restarted handle error, got: %v", err)
+	}
+	err = handles.check("db", []any{handle})
+	if err == nil || !strings.Contains(err.Error(), "has been released or was not created during this evaluation") {
+		t.Errorf("expected released handle error, got: %v", err)
+	}
+}
@@ -0,0 +1,141 @@
+package jpoet
+
+import (
+	"encoding/json"
+	"sync"
+
+	"github.com/google/go-jsonnet"
+	"github.com/marcbran/jpoet/internal/plugin"
+)
+
+type Host = plugin.Host
+
+type HostFunction = plugin.HostFunction
+
+func NewHostPlugin(name string, functions []jsonnet.NativeFunction, hostFunctions []HostFunction) *Plugin {
+	invoker := plugin.NewLocalHostInvoker(functions, hostFunctions)
+	return &Plugin{
+		name:    name,
+		invoker: invoker,
+		closer:  invoker,
+	}
+}
+
+type evalHost struct {
+	config *evalConfig
+
+	mu              sync.Mutex
+	defaultImporter jsonnet.Importer
+}
+
+func (h *evalHost) Evaluate(filename string, snippet string) (any, error) {
+	vm := jsonnet.MakeVM()
+	vm.Importer(hostImporter{host: h})
+	for _, f := range h.config.natives {
+		vm.NativeFunction(f)
+	}
+	out, err := vm.EvaluateAnonymousSnippet(filename, snippet)
+	if err != nil {
+		return nil, err
+	}
+	var value any
+	err = json.Unmarshal([]byte(out), &value)
+	if err != nil {
+		return nil, err
+	}
+	return value, nil
+}
+
+func (h *evalHost) Import(importedFrom string, importedPath string) (string, string, error) {
+	contents, foundAt, err := h.importContents(importedFrom, importedPath)
+	if err != nil {
+		return "", "", err
+	}
+	return contents.String(), foundAt, nil
+}
+
+func (h *evalHost) importContents(importedFrom string, importedPath string) (jsonnet.Contents, string, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if len(h.config.importer.Importers) == 0 {
+		if h.defaultImporter == nil {
+			h.defaultImporter = &jsonnet.FileImporter{}
+		}
+		return h.defaultImporter.Import(importedFrom, importedPath)
+	}
+	return h.config.importer.Import(importedFrom, importedPath)
+}
+
+type hostImporter struct {
+	host *evalHost
+}
+
+func (i hostImporter) Import(importedFrom string, importedPath string) (jsonnet.Contents, string, error) {
+	return i.host.importContents(importedFrom, importedPath)
+}
+
+type scopedInvoker struct {
+	name    string
+	invoker plugin.Invoker
+	host    Host
+	handles *handleTracker
+}
+
+func (s scopedInvoker) Invoke(funcName string, args []any) (any, error) {
+	err := s.check(args)
+	if err != nil {
+		return nil, err
+	}
+	res, err := plugin.InvokeWithHost(s.invoker, s.host, funcName, args)
+	if err != nil {
+		return nil, err
+	}
+	return s.track(res), nil
+}
+
+func (s scopedInvoker) InvokeBatch(calls []Call) ([]Result, error) {
+	results := make([]Result, len(calls))
+	var checked []Call
+	var indices []int
+	for i, call := range calls {
+		err := s.check(call.Args)
+		if err != nil {
+			results[i] = Result{Err: err}
+			continue
+		}
+		checked = append(checked, call)
+		indices = append(indices, i)
+	}
+	if len(checked) == 0 {
+		return results, nil
+	}
+	checkedResults, err := plugin.InvokeBatchWithHost(s.invoker, s.host, checked)
+	if err != nil {
+		return nil, err
+	}
+	for j, result := range checkedResults {
+		if result.Err == nil {
+			result.Value = s.track(result.Value)
+		}
+		results[indices[j]] = result
+	}
+	return results, nil
+}
+
+func (s scopedInvoker) Abort() {
+	plugin.Abort(s.invoker)
+}
+
+func (s scopedInvoker) check(args []any) error {
+	if s.handles == nil {
+		return nil
+	}
+	return s.handles.check(s.name, args)
+}
+
+func (s scopedInvoker) track(value any) any {
+	if s.handles == nil {
+		return value
+	}
+	return s.handles.track(s.name, s.invoker, value)
+}
@@ -0,0 +1,85 @@
+package jpoet
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/go-jsonnet"
+	"github.com/google/go-jsonnet/ast"
+)
+
+func newHostTestPlugin() *Plugin {
+	return NewHostPlugin("test", []jsonnet.NativeFunction{
+		{
+			Name:   "upper",
+			Params: ast.Identifiers{"s"},
+			Func: func(args []any) (any, error) {
+				return strings.ToUpper(args[0].(string)), nil
+			},
+		},
+	}, []HostFunction{
+		{
+			Name:   "evaluate",
+			Params: ast.Identifiers{"snippet"},
+			Func: func(host Host, args []any) (any, error) {
+				return host.Evaluate("snippet.jsonnet", args[0].(string))
+			},
+		},
+		{
+			Name:   "import",
+			Params: ast.Identifiers{"path"},
+			Func: func(host Host, args []any) (any, error) {
+				contents, _, err := host.Import("main.jsonnet", args[0].(string))
+				return contents, err
+			},
+		},
+	})
+}
+
+func TestHost_Evaluate(t *testing.T) {
+	var out map[string]any
+	err := Eval(
+		SnippetInput("main.jsonnet", `std.native('invoke:test')('evaluate', [
+			"(import 'lib.libsonnet') + { b: std.native('invoke:test')('upper', ['b']) }",
+		])`),
+		StringImport("lib.libsonnet", "{ a: 'a' }"),
+		WithPluginFunctions(newHostTestPlugin()),
+		Serialize(false),
+		ValueOutput(&out),
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if out["a"] != "a" || out["b"] != "B" {
+		t.Errorf("unexpected result: %v", out)
+	}
+}
+
+func TestHost_Import(t *testing.T) {
+	var out string
+	err := Eval(
+		SnippetInput("main.jsonnet", `std.native('invoke:test')('import', ['lib.libsonnet'])`),
+		StringImport("lib.libsonnet", "{ a: 'a' }"),
+		WithPluginFunctions(newHostTestPlugin()),
+		Serialize(false),
+		ValueOutput(&out),
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if out != "{ a: 'a' }" {
+		t.Errorf("unexpected result: %v", out)
+	}
+}
+
+func TestHost_ImportOutsideEvaluation(t *testing.T) {
+	err := Eval(
+		SnippetInput("main.jsonnet", `std.native('invoke:test')('import', ['secret.libsonnet'])`),
+		Importer(&MemoryImporter{Data: map[string]jsonnet.Contents{}}),
+		WithPluginFunctions(newHostTestPlugin()),
+		WriterOutput(&strings.Builder{}),
+	)
+	if err == nil || !strings.Contains(err.Error(), "import not available secret.libsonnet") {
+		t.Errorf("expected import error, got: %v", err)
+	}
+}
@@ -0,0 +1,233 @@
+package jpoet
+
+import (
+	"cmp"
+	"expvar"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+type MetricsReporter interface {
+	ObserveInvocation(pluginName string, funcName string, duration time.Duration, err error)
+}
+
+func MetricsMiddleware(reporters ...MetricsReporter) PluginMiddleware {
+	return func(info PluginInfo) Middleware {
+		return HookMiddleware(func(next Invoker, funcName string, args []any) (any, error) {
+			start := time.Now()
+			result, err := next.Invoke(funcName, args)
+			duration := time.Since(start)
+			for _, r := range reporters {
+				r.ObserveInvocation(info.Name, funcName, duration, err)
+			}
+			return result, err
+		})
+	}
+}
+
+var DefaultLatencyBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+type Metrics struct {
+	buckets []float64
+
+	mu        sync.Mutex
+	functions map[functionKey]*FunctionMetrics
+}
+
+type functionKey struct {
+	plugin   string
+	function string
+}
+
+type FunctionMetrics struct {
+	Plugin   string
+	Function string
+	Calls    uint64
+	Errors   uint64
+	Max      time.Duration
+	Latency  Histogram
+}
+
+type Histogram struct {
+	Bounds []float64
+	Counts []uint64
+	Count  uint64
+	Sum    float64
+}
+
+func (h *Histogram) observe(seconds float64) {
+	for i, bound := range h.Bounds {
+		if seconds <= bound {
+			h.Counts[i]++
+		}
+	}
+	h.Count++
+	h.Sum += seconds
+}
+
+func (h Histogram) Quantile(q float64) float64 {
+	if h.Count == 0 {
+		return 0
+	}
+	rank := q * float64(h.Count)
+	lower, below := 0.0, uint64(0)
+	for i, bound := range h.Bounds {
+		if float64(h.Counts[i]) >= rank {
+			inBucket := h.Counts[i] - below
+			if inBucket == 0 {
+				return bound
+			}
+			return lower + (bound-lower)*(rank-float64(below))/float64(inBucket)
+		}
+		lower, below = bound, h.Counts[i]
+	}
+	return lower
+}
+
+func NewMetrics(buckets ...float64) *Metrics {
+	if len(buckets) == 0 {
+		buckets = DefaultLatencyBuckets
+	}
+	buckets = slices.Clone(buckets)
+	slices.Sort(buckets)
+	return &Metrics{
+		buckets:   buckets,
+		functions: make(map[functionKey]*FunctionMetrics),
+	}
+}
+
+func (m *Metrics) ObserveInvocation(pluginName string, funcName string, duration time.Duration, err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	k := functionKey{plugin: pluginName, function: funcName}
+	f, ok := m.functions[k]
+	if !ok {
+		f = &FunctionMetrics{
+			Plugin:   pluginName,
+			Function: funcName,
+			Latency:  Histogram{Bounds: m.buckets, Counts: make([]uint64, len(m.buckets))},
+		}
+		m.functions[k] = f
+	}
+	f.Calls++
+	if err != nil {
+		f.Errors++
+	}
+	f.Max = max(f.Max, duration)
+	f.Latency.observe(duration.Seconds())
+}
+
+func (m *Metrics) Snapshot() []FunctionMetrics {
+	m.mu.Lock()
+	snapshot := make([]FunctionMetrics, 0, len(m.functions))
+	for _, f := range m.functions {
+		c := *f
+		c.Latency.Counts = slices.Clone(f.Latency.Counts)
+		snapshot = append(snapshot, c)
+	}
+	m.mu.Unlock()
+	slices.SortFunc(snapshot, func(a, b FunctionMetrics) int {
+		return cmp.Or(cmp.Compare(a.Plugin, b.Plugin), cmp.Compare(a.Function, b.Function))
+	})
+	return snapshot
+}
+
+func (m *Metrics) WritePrometheus(w io.Writer) error {
+	snapshot := m.Snapshot()
+	var sb strings.Builder
+	sb.WriteString("# HELP jpoet_plugin_invocations_total Number of invocations of plugin functions.\n")
+	sb.WriteString("# TYPE jpoet_plugin_invocations_total counter\n")
+	for _, f := range snapshot {
+		fmt.Fprintf(&sb, "jpoet_plugin_invocations_total{%s} %d\n", prometheusLabels(f), f.Calls)
+	}
+	sb.WriteString("# HELP jpoet_plugin_invocation_errors_total Number of invocations of plugin functions that failed.\n")
+	sb.WriteString("# TYPE jpoet_plugin_invocation_errors_total counter\n")
+	for _, f := range snapshot {
+		fmt.Fprintf(&sb, "jpoet_plugin_invocation_errors_total{%s} %d\n", prometheusLabels(f), f.Errors)
+	}
+	sb.WriteString("# HELP jpoet_plugin_invocation_duration_seconds Duration of invocations of plugin functions.\n")
+	sb.WriteString("# TYPE jpoet_plugin_invocation_duration_seconds histogram\n")
+	for _, f := range snapshot {
+		labels := prometheusLabels(f)
+		for i, bound := range f.Latency.Bounds {
+			fmt.Fprintf(&sb, "jpoet_plugin_invocation_duration_seconds_bucket{%s,le=%q} %d\n", labels, formatFloat(bound), f.Latency.Counts[i])
+		}
+		fmt.Fprintf(&sb, "jpoet_plugin_invocation_duration_seconds_bucket{%s,le=\"+Inf\"} %d\n", labels, f.Latency.Count)
+		fmt.Fprintf(&sb, "jpoet_plugin_invocation_duration_seconds_sum{%s} %s\n", labels, formatFloat(f.Latency.Sum))
+		fmt.Fprintf(&sb, "jpoet_plugin_invocation_duration_seconds_count{%s} %d\n", labels, f.Latency.Count)
+	}
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+func prometheusLabels(f FunctionMetrics) string {
+	return fmt.Sprintf("plugin=%s,function=%s", prometheusLabelValue(f.Plugin), prometheusLabelValue(f.Function))
+}
+
+var prometheusEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func prometheusLabelValue(s string) string {
+	return `"` + prometheusEscaper.Replace(s) + `"`
+}
+
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
+
+type ExpvarReporter struct {
+	vars *expvar.Map
+}
+
+func NewExpvarReporter(name string) *ExpvarReporter {
+	expvarMu.Lock()
+	defer expvarMu.Unlock()
+	vars, ok := expvar.Get(name).(*expvar.Map)
+	if !ok {
+		vars = expvar.NewMap(name)
+	}
+	return &ExpvarReporter{vars: vars}
+}
+
+var expvarMu sync.Mutex
+
+func (r *ExpvarReporter) ObserveInvocation(pluginName string, funcName string, duration time.Duration, err error) {
+	f := r.function(pluginName + "." + funcName)
+	f.Add("calls", 1)
+	if err != nil {
+		f.Add("errors", 1)
+	}
+	f.AddFloat("durationSeconds", duration.Seconds())
+	buckets := f.Get("buckets").(*expvar.Map)
+	for _, bound := range DefaultLatencyBuckets {
+		if duration.Seconds() <= bound {
+			buckets.Add(formatFloat(bound), 1)
+		}
+	}
+}
+
+func (r *ExpvarReporter) function(name string) *expvar.Map {
+	if f, ok := r.vars.Get(name).(*expvar.Map); ok {
+		return f
+	}
+	expvarMu.Lock()
+	defer expvarMu.Unlock()
+	if f, ok := r.vars.Get(name).(*expvar.Map); ok {
+		return f
+	}
+	f := new(expvar.Map).Init()
+	f.Set("calls", new(expvar.Int))
+	f.Set("errors", new(expvar.Int))
+	f.Set("durationSeconds", new(expvar.Float))
+	buckets := new(expvar.Map).Init()
+	for _, bound := range DefaultLatencyBuckets {
+		buckets.Set(formatFloat(bound), new(expvar.Int))
+	}
+	f.Set("buckets", buckets)
+	r.vars.Set(name, f)
+	return f
+}
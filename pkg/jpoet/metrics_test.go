@@ -0,0 +1,128 @@
+package jpoet
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"expvar"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestMetricsMiddleware(t *testing.T) {
+	metrics := NewMetrics()
+	invoker := MetricsMiddleware(metrics)(PluginInfo{Name: "test"})(&failingInvoker{failures: 1, err: errors.New("busy")})
+
+	for range 3 {
+		_, _ = invoker.Invoke("fetch", []any{})
+	}
+
+	snapshot := metrics.Snapshot()
+	if len(snapshot) != 1 {
+		t.Fatalf("expected 1 function, got %d", len(snapshot))
+	}
+	f := snapshot[0]
+	if f.Plugin != "test" || f.Function != "fetch" || f.Calls != 3 || f.Errors != 1 || f.Latency.Count != 3 {
+		t.Errorf("unexpected metrics: %+v", f)
+	}
+}
+
+func TestMetrics_Histogram(t *testing.T) {
+	metrics := NewMetrics(0.1, 1)
+	for _, d := range []time.Duration{50 * time.Millisecond, 500 * time.Millisecond, 2 * time.Second} {
+		metrics.ObserveInvocation("test", "fetch", d, nil)
+	}
+
+	f := metrics.Snapshot()[0]
+	if f.Latency.Counts[0] != 1 || f.Latency.Counts[1] != 2 || f.Latency.Count != 3 {
+		t.Errorf("unexpected buckets: %+v", f.Latency)
+	}
+	if f.Max != 2*time.Second {
+		t.Errorf("expected max of 2s, got %s", f.Max)
+	}
+	if q := f.Latency.Quantile(0.5); q < 0.1 || q > 1 {
+		t.Errorf("expected median between 0.1 and 1, got %v", q)
+	}
+	if q := f.Latency.Quantile(0.99); q != 1 {
+		t.Errorf("expected quantile above the buckets to be the last bound, got %v", q)
+	}
+}
+
+func TestMetrics_WritePrometheus(t *testing.T) {
+	metrics := NewMetrics(0.1, 1)
+	metrics.ObserveInvocation("test", "fetch", 50*time.Millisecond, nil)
+	metrics.ObserveInvocation("test", "fetch", 2*time.Second, errors.New("timeout"))
+
+	var buf bytes.Buffer
+	err := metrics.WritePrometheus(&buf)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, line := range []string{
+		"# TYPE jpoet_plugin_invocations_total counter",
+		`jpoet_plugin_invocations_total{plugin="test",function="fetch"} 2`,
+		`jpoet_plugin_invocation_errors_total{plugin="test",function="fetch"} 1`,
+		"# TYPE jpoet_plugin_invocation_duration_seconds histogram",
+		`jpoet_plugin_invocation_duration_seconds_bucket{plugin="test",function="fetch",le="0.1"} 1`,
+		`jpoet_plugin_invocation_duration_seconds_bucket{plugin="test",function="fetch",le="1"} 1`,
+		`jpoet_plugin_invocation_duration_seconds_bucket{plugin="test",function="fetch",le="+Inf"} 2`,
+		`jpoet_plugin_invocation_duration_seconds_sum{plugin="test",function="fetch"} 2.05`,
+		`jpoet_plugin_invocation_duration_seconds_count{plugin="test",function="fetch"} 2`,
+	} {
+		if !strings.Contains(buf.String(), line+"\n") {
+			t.Errorf("expected line %q in:\n%s", line, buf.String())
+		}
+	}
+}
+
+// expvarRuns makes the names of the variables unique per run, as expvar variables cannot be unpublished.
+var expvarRuns atomic.Int32
+
+func TestExpvarReporter(t *testing.T) {
+	name := fmt.Sprintf("jpoet_test_plugins_%d", expvarRuns.Add(1))
+	reporter := NewExpvarReporter(name)
+	reporter.ObserveInvocation("test", "fetch", 20*time.Millisecond, nil)
+	reporter.ObserveInvocation("test", "fetch", 20*time.Millisecond, errors.New("busy"))
+
+	var vars map[string]map[string]any
+	err := json.Unmarshal([]byte(expvar.Get(name).String()), &vars)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	f := vars["test.fetch"]
+	if f["calls"] != 2.0 || f["errors"] != 1.0 {
+		t.Errorf("unexpected vars: %v", f)
+	}
+	buckets := f["buckets"].(map[string]any)
+	if buckets["0.01"] != 0.0 || buckets["0.025"] != 2.0 {
+		t.Errorf("unexpected buckets: %v", buckets)
+	}
+	if NewExpvarReporter(name).vars != reporter.vars {
+		t.Errorf("expected reporters with the same name to share their variables")
+	}
+}
+
+func TestExpvarReporter_Concurrent(t *testing.T) {
+	name := fmt.Sprintf("jpoet_test_plugins_%d", expvarRuns.Add(1))
+	reporters := make([]*ExpvarReporter, 8)
+	var wg sync.WaitGroup
+	for i := range reporters {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			reporters[i] = NewExpvarReporter(name)
+		}()
+	}
+	wg.Wait()
+
+	for _, r := range reporters {
+		if r.vars != reporters[0].vars {
+			t.Errorf("expected concurrently created reporters to share their variables")
+			break
+		}
+	}
+}
@@ -2,10 +2,15 @@ package jpoet
 
 import (
 	"errors"
+	"fmt"
 	"io"
 	"io/fs"
+	"maps"
 	"os"
 	"path/filepath"
+	"slices"
+	"strings"
+	"time"
 
 	"github.com/google/go-jsonnet"
 	"github.com/marcbran/jpoet/internal/plugin"
@@ -13,28 +18,116 @@ import (
 
 type Plugin struct {
 	name       string
+	version    string
 	invoker    plugin.Invoker
 	closer     io.Closer
+	configure  plugin.ConfigureFunc
+	cacheHints map[string]CacheHint
 	middleware []Middleware
 }
 
+type PluginConfig = plugin.ProcessConfig
+
+type PluginLimits = plugin.Limits
+
+type PluginSandbox = plugin.Sandbox
+
+// Trampoline has to be called first thing in main for plugins to be sandboxed and limited before they run.
+func Trampoline() {
+	plugin.Trampoline()
+}
+
+type PluginManifest = plugin.Manifest
+
+type CacheHint = plugin.CacheHint
+
+type PluginTimeouts = plugin.Timeouts
+
+type RetryPolicy = plugin.RetryPolicy
+
+const PluginManifestFile = plugin.ManifestFile
+
+func ReadPluginManifest(pluginDir string) (*PluginManifest, error) {
+	return plugin.ReadManifest(pluginDir)
+}
+
+func PluginSHA256(path string) (string, error) {
+	return plugin.FileSHA256(path)
+}
+
 func NewPlugin(name string, functions []jsonnet.NativeFunction) *Plugin {
+	invoker := plugin.NewLocalInvoker(functions)
 	return &Plugin{
 		name:    name,
-		invoker: plugin.NewLocalInvoker(functions),
+		invoker: invoker,
+		closer:  invoker,
 	}
 }
 
-func NewClientPlugin(name string, path string) (*Plugin, error) {
-	invoker, err := plugin.NewClientInvoker(name, path)
+type PluginOption func(*PluginConfig)
+
+func PluginProcessConfig(config PluginConfig) PluginOption {
+	return func(c *PluginConfig) {
+		*c = config
+	}
+}
+
+func newPluginConfig(opts []PluginOption) PluginConfig {
+	var config PluginConfig
+	for _, opt := range opts {
+		opt(&config)
+	}
+	return config
+}
+
+func NewClientPlugin(name string, path string, opts ...PluginOption) (*Plugin, error) {
+	config := newPluginConfig(opts)
+	invoker, err := plugin.NewClientInvoker(name, path, config)
 	if err != nil {
 		return nil, err
 	}
-	return &Plugin{
+	return newProcessPlugin(name, invoker, config), nil
+}
+
+func NewStdioPlugin(name string, path string, opts ...PluginOption) (*Plugin, error) {
+	config := newPluginConfig(opts)
+	invoker, err := plugin.NewStdioInvoker(name, path, config)
+	if err != nil {
+		return nil, err
+	}
+	return newProcessPlugin(name, invoker, config), nil
+}
+
+func NewReattachPlugin(name string, reattachConfig ReattachConfig, opts ...PluginOption) (*Plugin, error) {
+	config := newPluginConfig(opts)
+	invoker, err := plugin.NewReattachInvoker(name, reattachConfig, config)
+	if err != nil {
+		return nil, err
+	}
+	return newProcessPlugin(name, invoker, config), nil
+}
+
+func newProcessPlugin(name string, invoker plugin.InvokeCloser, config PluginConfig) *Plugin {
+	p := &Plugin{
 		name:    name,
 		invoker: invoker,
 		closer:  invoker,
-	}, nil
+	}
+	if !config.Timeouts.IsZero() {
+		p.middleware = append(p.middleware, TimeoutMiddleware(config.Timeouts))
+	}
+	if config.Retry.Attempts > 1 {
+		p.middleware = append(p.middleware, RetryMiddleware(config.Retry))
+	}
+	return p
+}
+
+type ReattachConfig = plugin.ReattachConfig
+
+const ReattachEnv = plugin.ReattachEnv
+
+func ParseReattachConfigs(s string) (map[string]ReattachConfig, error) {
+	return plugin.ParseReattachConfigs(s)
 }
 
 type Invoker = plugin.Invoker
@@ -42,12 +135,72 @@ type Invoker = plugin.Invoker
 type Middleware func(Invoker) Invoker
 
 func (p *Plugin) WithMiddleware(middleware ...Middleware) *Plugin {
-	return &Plugin{
-		name:       p.name,
-		invoker:    p.invoker,
-		closer:     p.closer,
-		middleware: append(p.middleware, middleware...),
+	c := *p
+	c.middleware = append(slices.Clone(p.middleware), middleware...)
+	return &c
+}
+
+type PluginInfo struct {
+	Name    string
+	Version string
+
+	plugin *Plugin
+}
+
+func (i PluginInfo) CacheHint(funcName string) CacheHint {
+	if i.plugin == nil {
+		return CacheHint{}
+	}
+	if hint, ok := i.plugin.cacheHints[funcName]; ok {
+		return hint
+	}
+	d, ok := i.plugin.invoker.(interface{ Functions() []plugin.FunctionInfo })
+	if !ok {
+		return CacheHint{}
+	}
+	for _, f := range d.Functions() {
+		if f.Name == funcName {
+			return f.Cache
+		}
+	}
+	return CacheHint{}
+}
+
+func (i PluginInfo) CacheTTL(maxTTL time.Duration) func(funcName string, args []any) time.Duration {
+	return func(funcName string, args []any) time.Duration {
+		hint := i.CacheHint(funcName)
+		switch {
+		case hint.Never:
+			return 0
+		case hint.Pure:
+			return maxTTL
+		default:
+			return min(hint.TTL, maxTTL)
+		}
+	}
+}
+
+type PluginMiddleware func(info PluginInfo) Middleware
+
+func (p *Plugin) WithPluginMiddleware(middleware ...PluginMiddleware) *Plugin {
+	info := PluginInfo{Name: p.name, Version: p.version, plugin: p}
+	applied := make([]Middleware, 0, len(middleware))
+	for _, m := range middleware {
+		applied = append(applied, m(info))
 	}
+	return p.WithMiddleware(applied...)
+}
+
+func (p *Plugin) WithConfigure(configure func(settings map[string]any) error) *Plugin {
+	c := *p
+	c.configure = configure
+	return &c
+}
+
+func (p *Plugin) WithCacheHints(hints map[string]CacheHint) *Plugin {
+	c := *p
+	c.cacheHints = hints
+	return &c
 }
 
 type InvokeHook func(next Invoker, funcName string, args []any) (any, error)
@@ -61,6 +214,25 @@ func (h hookInvoker) Invoke(funcName string, args []any) (any, error) {
 	return h.hook(h.next, funcName, args)
 }
 
+func (h hookInvoker) block() func() {
+	return block(h.next)
+}
+
+func (h hookInvoker) Abort() {
+	plugin.Abort(h.next)
+}
+
+type blocker interface {
+	block() (unblock func())
+}
+
+func block(invoker Invoker) func() {
+	if b, ok := invoker.(blocker); ok {
+		return b.block()
+	}
+	return func() {}
+}
+
 func HookMiddleware(hook InvokeHook) Middleware {
 	return func(next Invoker) Invoker {
 		return hookInvoker{next: next, hook: hook}
@@ -71,38 +243,256 @@ func (p *Plugin) WithHook(hook InvokeHook) *Plugin {
 	return p.WithMiddleware(HookMiddleware(hook))
 }
 
+type PluginsOption func(*pluginsConfig)
+
+type pluginsConfig struct {
+	dir        string
+	installed  map[string]string
+	missing    map[string]error
+	configs    map[string]PluginConfig
+	reattach   map[string]ReattachConfig
+	middleware []Middleware
+}
+
+func PluginsDir(dir string) PluginsOption {
+	return func(c *pluginsConfig) {
+		c.dir = dir
+	}
+}
+
+func PluginsInstalled(dirs map[string]string) PluginsOption {
+	return func(c *pluginsConfig) {
+		c.installed = dirs
+	}
+}
+
+func PluginsMissing(errs map[string]error) PluginsOption {
+	return func(c *pluginsConfig) {
+		c.missing = errs
+	}
+}
+
+func PluginsConfig(configs map[string]PluginConfig) PluginsOption {
+	return func(c *pluginsConfig) {
+		c.configs = configs
+	}
+}
+
+func PluginsReattach(configs map[string]ReattachConfig) PluginsOption {
+	return func(c *pluginsConfig) {
+		c.reattach = configs
+	}
+}
+
+func PluginsMiddleware(middleware ...Middleware) PluginsOption {
+	return func(c *pluginsConfig) {
+		c.middleware = append(c.middleware, middleware...)
+	}
+}
+
+func LoadPlugins(opts ...PluginsOption) ([]*Plugin, error) {
+	c := &pluginsConfig{}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c.load()
+}
+
 func NewPluginsDir(pluginsDir string, middleware ...Middleware) ([]*Plugin, error) {
-	entries, err := readPluginEntries(pluginsDir)
+	return LoadPlugins(PluginsDir(pluginsDir), PluginsMiddleware(middleware...))
+}
+
+func (c *pluginsConfig) load() (plugins []*Plugin, err error) {
+	defer func() {
+		if err != nil {
+			for _, p := range plugins {
+				_ = p.Close()
+			}
+			plugins = nil
+		}
+	}()
+	for _, name := range slices.Sorted(maps.Keys(c.installed)) {
+		if _, ok := c.reattach[name]; ok {
+			continue
+		}
+		p, err := c.newInstalledPlugin(name, c.installed[name])
+		if err != nil {
+			return plugins, err
+		}
+		plugins = append(plugins, p)
+	}
+	sources := make(map[string]string)
+	if c.dir != "" {
+		entries, err := readPluginEntries(c.dir)
+		if err != nil {
+			return plugins, err
+		}
+		for _, entry := range entries {
+			pluginDir := filepath.Join(c.dir, entry.Name())
+			p, err := c.newDirPlugin(entry.Name(), pluginDir)
+			if err != nil {
+				return plugins, err
+			}
+			_, installed := c.installed[p.name]
+			_, reattached := c.reattach[p.name]
+			if installed || reattached {
+				_ = p.Close()
+				continue
+			}
+			if other, ok := sources[p.name]; ok {
+				_ = p.Close()
+				return plugins, fmt.Errorf("plugins in %s and %s both provide invoke:%s", other, pluginDir, p.name)
+			}
+			sources[p.name] = pluginDir
+			plugins = append(plugins, p)
+		}
+	}
+	for _, name := range slices.Sorted(maps.Keys(c.missing)) {
+		_, found := sources[name]
+		_, reattached := c.reattach[name]
+		if found || reattached {
+			continue
+		}
+		plugins = append(plugins, &Plugin{
+			name:    name,
+			invoker: missingInvoker{err: c.missing[name]},
+		})
+	}
+	names := slices.Sorted(maps.Keys(c.reattach))
+	for _, name := range names {
+		p, err := NewReattachPlugin(name, c.reattach[name], PluginProcessConfig(c.configs[name]))
+		if err != nil {
+			return plugins, err
+		}
+		plugins = append(plugins, p)
+	}
+	if len(c.middleware) > 0 {
+		for i, p := range plugins {
+			plugins[i] = p.WithMiddleware(c.middleware...)
+		}
+	}
+	return plugins, nil
+}
+
+type missingInvoker struct {
+	err error
+}
+
+func (i missingInvoker) Invoke(funcName string, args []any) (any, error) {
+	return nil, i.err
+}
+
+const protocolMarker = "protocol"
+
+func (c *pluginsConfig) newDirPlugin(base string, pluginDir string) (*Plugin, error) {
+	manifest, err := ReadPluginManifest(pluginDir)
 	if err != nil {
 		return nil, err
 	}
-	var plugins []*Plugin
-	for _, entry := range entries {
-		name := entry.Name()
-		p, err := NewClientPlugin(name, filepath.Join(pluginsDir, name, name))
+	if manifest == nil {
+		protocol, err := readProtocol(pluginDir)
 		if err != nil {
 			return nil, err
 		}
-		if len(middleware) > 0 {
-			p = p.WithMiddleware(middleware...)
+		name := strings.TrimPrefix(base, "jsonnet-plugin-")
+		return newDirPluginAt(name, filepath.Join(pluginDir, base), protocol, c.configs[name])
+	}
+	if manifest.Name != base {
+		return nil, fmt.Errorf("plugin directory %s holds %s according to its manifest, expected %s", pluginDir, manifest.Name, base)
+	}
+	config := c.configs[manifest.NativeName]
+	config.SHA256 = manifest.SHA256
+	return newManifestPlugin(manifest.NativeName, pluginDir, *manifest, config)
+}
+
+func (c *pluginsConfig) newInstalledPlugin(name string, pluginDir string) (*Plugin, error) {
+	manifest, err := ReadPluginManifest(pluginDir)
+	if err != nil {
+		return nil, err
+	}
+	if manifest == nil {
+		return nil, fmt.Errorf("plugin %s in %s has no manifest, run jpoet install to reinstall it", name, pluginDir)
+	}
+	config := c.configs[name]
+	config.SHA256 = manifest.SHA256
+	return newManifestPlugin(name, pluginDir, *manifest, config)
+}
+
+func newManifestPlugin(name string, pluginDir string, manifest PluginManifest, config PluginConfig) (*Plugin, error) {
+	p, err := newDirPluginAt(name, filepath.Join(pluginDir, manifest.Name), manifest.Protocol, config)
+	if err != nil {
+		return nil, err
+	}
+	p.version = manifest.Version
+	return p, nil
+}
+
+func newDirPluginAt(name string, path string, protocol string, config PluginConfig) (*Plugin, error) {
+	switch protocol {
+	case "", "grpc":
+		return NewClientPlugin(name, path, PluginProcessConfig(config))
+	case "stdio":
+		return NewStdioPlugin(name, path, PluginProcessConfig(config))
+	default:
+		return nil, fmt.Errorf("plugin %s uses unknown protocol %q", name, protocol)
+	}
+}
+
+func readProtocol(pluginDir string) (string, error) {
+	b, err := os.ReadFile(filepath.Join(pluginDir, protocolMarker))
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return "", nil
 		}
-		plugins = append(plugins, p)
+		return "", err
 	}
-	return plugins, nil
+	return strings.TrimSpace(string(b)), nil
 }
 
 func (p *Plugin) Serve() {
-	plugin.NewConsumer(p.name, p.invoker).Serve()
+	plugin.NewConsumer(p.name, p.invoker).WithConfigure(p.configure).WithCacheHints(p.cacheHints).Serve()
 }
 
 func (p *Plugin) NativeFunction() *jsonnet.NativeFunction {
-	invoker := plugin.Invoker(p.invoker)
+	return p.nativeFunction(p.scoped(nil, nil))
+}
+
+func (p *Plugin) BatchNativeFunction() *jsonnet.NativeFunction {
+	return p.batchNativeFunction(p.scoped(nil, nil))
+}
+
+func (p *Plugin) scoped(host Host, handles *handleTracker) scopedInvoker {
+	return scopedInvoker{
+		name:    p.name,
+		invoker: p.invoker,
+		host:    host,
+		handles: handles,
+	}
+}
+
+func (p *Plugin) nativeFunctions(host Host, handles *handleTracker) []*jsonnet.NativeFunction {
+	scoped := p.scoped(host, handles)
+	return []*jsonnet.NativeFunction{
+		p.nativeFunction(scoped),
+		p.batchNativeFunction(scoped),
+	}
+}
+
+func (p *Plugin) nativeFunction(scoped scopedInvoker) *jsonnet.NativeFunction {
+	invoker := plugin.Invoker(scoped)
 	for _, m := range p.middleware {
 		invoker = m(invoker)
 	}
 	return plugin.NewConsumer(p.name, invoker).Function()
 }
 
+func (p *Plugin) batchNativeFunction(scoped scopedInvoker) *jsonnet.NativeFunction {
+	return plugin.NewConsumer(p.name, batchInvoker{
+		invoker:    scoped,
+		middleware: p.middleware,
+	}).BatchFunction()
+}
+
 func (p *Plugin) Close() error {
 	if p.closer != nil {
 		return p.closer.Close()
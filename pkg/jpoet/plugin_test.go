@@ -0,0 +1,168 @@
+package jpoet
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/google/go-jsonnet"
+)
+
+func writeManifestPlugin(t *testing.T, pluginsDir string, manifest PluginManifest) string {
+	t.Helper()
+	return writeManifestPluginAt(t, filepath.Join(pluginsDir, "jsonnet-plugin-test"), manifest)
+}
+
+func writeManifestPluginAt(t *testing.T, pluginDir string, manifest PluginManifest) string {
+	t.Helper()
+	err := os.MkdirAll(pluginDir, 0o755)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	path := filepath.Join(pluginDir, manifest.Name)
+	err = os.WriteFile(path, []byte("#!/bin/sh\n"), 0o755)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if manifest.SHA256 == "" {
+		manifest.SHA256, err = PluginSHA256(path)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	err = manifest.Write(pluginDir)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return path
+}
+
+func TestLoadPlugins_Manifest(t *testing.T) {
+	pluginsDir := t.TempDir()
+	path := writeManifestPlugin(t, pluginsDir, PluginManifest{
+		Name:       "jsonnet-plugin-test",
+		NativeName: "test",
+		Version:    "v1.0.0",
+		Source:     "github.com/marcbran/jsonnet-plugin-test",
+	})
+	err := os.WriteFile(path, []byte("#!/bin/sh\necho tampered\n"), 0o755)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	plugins, err := LoadPlugins(PluginsDir(pluginsDir))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer func() {
+		_ = plugins[0].Close()
+	}()
+	if plugins[0].name != "test" {
+		t.Errorf("expected plugin test, got %s", plugins[0].name)
+	}
+	_, err = plugins[0].invoker.Invoke("upper", []any{"a"})
+	if err == nil || !strings.Contains(err.Error(), "plugin test has been modified since it was installed") {
+		t.Errorf("expected checksum error, got: %v", err)
+	}
+}
+
+func TestLoadPlugins_ManifestMismatch(t *testing.T) {
+	pluginsDir := t.TempDir()
+	writeManifestPlugin(t, pluginsDir, PluginManifest{
+		Name:       "jsonnet-plugin-other",
+		NativeName: "other",
+		SHA256:     "abc",
+	})
+
+	_, err := LoadPlugins(PluginsDir(pluginsDir))
+	if err == nil || !strings.Contains(err.Error(), "holds jsonnet-plugin-other according to its manifest, expected jsonnet-plugin-test") {
+		t.Errorf("expected manifest mismatch error, got: %v", err)
+	}
+}
+
+func TestLoadPlugins_Alias(t *testing.T) {
+	store := t.TempDir()
+	installed := make(map[string]string)
+	for _, version := range []string{"v0", "v1"} {
+		pluginDir := filepath.Join(store, "jsonnet-plugin-test", version)
+		writeManifestPluginAt(t, pluginDir, PluginManifest{
+			Name:       "jsonnet-plugin-test",
+			NativeName: "test",
+			Version:    version,
+			Source:     "github.com/marcbran/jsonnet-plugin-test",
+		})
+		installed["test@"+version] = pluginDir
+	}
+
+	plugins, err := LoadPlugins(PluginsInstalled(installed))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var names []string
+	for _, p := range plugins {
+		names = append(names, p.name)
+		_ = p.Close()
+	}
+	if !slices.Equal(names, []string{"test@v0", "test@v1"}) {
+		t.Errorf("expected plugins test@v0 and test@v1, got %v", names)
+	}
+}
+
+func TestLoadPlugins_Missing(t *testing.T) {
+	plugins, err := LoadPlugins(PluginsMissing(map[string]error{
+		"test": errors.New("plugin marcbran/jsonnet-plugin-test v1 is not installed"),
+	}))
+	if err != nil {
+		t.Fatalf("expected missing plugins not to fail loading, got: %v", err)
+	}
+
+	var out string
+	err = Eval(
+		SnippetInput("main.jsonnet", `'unused'`),
+		WithPluginFunctions(plugins...),
+		Serialize(false),
+		ValueOutput(&out),
+	)
+	if err != nil || out != "unused" {
+		t.Fatalf("expected evaluation without invocations to succeed, got: %v, %v", out, err)
+	}
+
+	err = Eval(
+		SnippetInput("main.jsonnet", `std.native('invoke:test')('upper', ['a'])`),
+		WithPluginFunctions(plugins...),
+		ValueOutput(&out),
+	)
+	if err == nil || !strings.Contains(err.Error(), "plugin marcbran/jsonnet-plugin-test v1 is not installed") {
+		t.Errorf("expected missing plugin error, got: %v", err)
+	}
+}
+
+func TestLoadPlugins_Collision(t *testing.T) {
+	pluginsDir := t.TempDir()
+	for _, name := range []string{"jsonnet-plugin-a", "jsonnet-plugin-b"} {
+		writeManifestPluginAt(t, filepath.Join(pluginsDir, name), PluginManifest{
+			Name:       name,
+			NativeName: "test",
+		})
+	}
+
+	_, err := LoadPlugins(PluginsDir(pluginsDir))
+	if err == nil || !strings.Contains(err.Error(), "both provide invoke:test") {
+		t.Errorf("expected collision error, got: %v", err)
+	}
+}
+
+func TestEval_PluginCollision(t *testing.T) {
+	var out string
+	err := Eval(
+		SnippetInput("main.jsonnet", `std.native('invoke:test')('upper', ['a'])`),
+		WithPluginFunctions(NewPlugin("test", []jsonnet.NativeFunction{}), NewPlugin("test", []jsonnet.NativeFunction{})),
+		ValueOutput(&out),
+	)
+	if err == nil || !strings.Contains(err.Error(), "more than one plugin provides invoke:test") {
+		t.Errorf("expected collision error, got: %v", err)
+	}
+}
@@ -0,0 +1,93 @@
+package jpoet
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+
+	"github.com/google/go-jsonnet"
+)
+
+type PluginPolicy struct {
+	Allow []string `json:"allow"`
+	Deny  []string `json:"deny"`
+}
+
+type PolicyError struct {
+	Plugin   string
+	FuncName string
+	Rule     string
+}
+
+func (e PolicyError) Error() string {
+	if e.Rule == "" {
+		return fmt.Sprintf("invocation of %s.%s is denied by policy, as it matches no allow rule", e.Plugin, e.FuncName)
+	}
+	return fmt.Sprintf("invocation of %s.%s is denied by policy rule deny %s", e.Plugin, e.FuncName, e.Rule)
+}
+
+func LoadPluginPolicy(file string) (PluginPolicy, error) {
+	b, err := os.ReadFile(file)
+	if err != nil {
+		return PluginPolicy{}, fmt.Errorf("failed to read policy: %w", err)
+	}
+	out, err := jsonnet.MakeVM().EvaluateAnonymousSnippet(file, string(b))
+	if err != nil {
+		return PluginPolicy{}, fmt.Errorf("failed to evaluate policy: %w", err)
+	}
+	var policy PluginPolicy
+	err = json.Unmarshal([]byte(out), &policy)
+	if err != nil {
+		return PluginPolicy{}, fmt.Errorf("failed to parse policy %s: %w", file, err)
+	}
+	err = policy.Validate()
+	if err != nil {
+		return PluginPolicy{}, err
+	}
+	return policy, nil
+}
+
+func (p PluginPolicy) IsZero() bool {
+	return len(p.Allow) == 0 && len(p.Deny) == 0
+}
+
+func (p PluginPolicy) Validate() error {
+	for _, rule := range append(append([]string(nil), p.Allow...), p.Deny...) {
+		_, err := path.Match(rule, "")
+		if err != nil {
+			return fmt.Errorf("invalid policy rule %q: %w", rule, err)
+		}
+	}
+	return nil
+}
+
+func (p PluginPolicy) Check(pluginName string, funcName string) error {
+	name := pluginName + "." + funcName
+	for _, rule := range p.Deny {
+		if ok, _ := path.Match(rule, name); ok {
+			return PolicyError{Plugin: pluginName, FuncName: funcName, Rule: rule}
+		}
+	}
+	if len(p.Allow) == 0 {
+		return nil
+	}
+	for _, rule := range p.Allow {
+		if ok, _ := path.Match(rule, name); ok {
+			return nil
+		}
+	}
+	return PolicyError{Plugin: pluginName, FuncName: funcName}
+}
+
+func (p PluginPolicy) Middleware() PluginMiddleware {
+	return func(info PluginInfo) Middleware {
+		return HookMiddleware(func(next Invoker, funcName string, args []any) (any, error) {
+			err := p.Check(info.Name, funcName)
+			if err != nil {
+				return nil, err
+			}
+			return next.Invoke(funcName, args)
+		})
+	}
+}
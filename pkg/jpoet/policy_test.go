@@ -0,0 +1,102 @@
+package jpoet
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestPluginPolicy_Check(t *testing.T) {
+	policy := PluginPolicy{
+		Allow: []string{"github.*", "http.get"},
+		Deny:  []string{"github.delete*"},
+	}
+	for name, expected := range map[string]string{
+		"github.repo":       "",
+		"http.get":          "",
+		"http.post":         "invocation of http.post is denied by policy, as it matches no allow rule",
+		"github.deleteRepo": "invocation of github.deleteRepo is denied by policy rule deny github.delete*",
+	} {
+		pluginName, funcName, _ := strings.Cut(name, ".")
+		err := policy.Check(pluginName, funcName)
+		if expected == "" {
+			if err != nil {
+				t.Errorf("expected %s to be allowed, got: %v", name, err)
+			}
+			continue
+		}
+		if err == nil || err.Error() != expected {
+			t.Errorf("expected %q for %s, got: %v", expected, name, err)
+		}
+	}
+}
+
+func TestPluginPolicy_DenyOnly(t *testing.T) {
+	policy := PluginPolicy{Deny: []string{"*.exec"}}
+	if err := policy.Check("shell", "read"); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	var policyErr PolicyError
+	if err := policy.Check("shell", "exec"); !errors.As(err, &policyErr) || policyErr.Rule != "*.exec" {
+		t.Errorf("expected policy error, got: %v", err)
+	}
+}
+
+func TestPluginPolicy_Middleware(t *testing.T) {
+	next := &countingInvoker{}
+	invoker := PluginPolicy{Allow: []string{"test.upper"}}.Middleware()(PluginInfo{Name: "test"})(next)
+
+	_, err := invoker.Invoke("upper", []any{"a"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	_, err = invoker.Invoke("lower", []any{"A"})
+	if err == nil {
+		t.Fatalf("expected error")
+	}
+	if next.calls != 1 {
+		t.Errorf("expected denied invocation not to reach the plugin, got %d calls", next.calls)
+	}
+}
+
+func TestEval_PluginPolicy(t *testing.T) {
+	p := NewPlugin("test", nil).WithHook(func(next Invoker, funcName string, args []any) (any, error) {
+		return "ok", nil
+	}).WithPluginMiddleware(PluginPolicy{Deny: []string{"test.secret"}}.Middleware())
+
+	var sb strings.Builder
+	err := Eval(
+		WithPlugin(p),
+		SnippetInput("main.jsonnet", `std.native('invoke:test')('secret', [])`),
+		WriterOutput(&sb),
+	)
+	if err == nil || !strings.Contains(err.Error(), "invocation of test.secret is denied by policy rule deny test.secret") {
+		t.Errorf("expected policy error, got: %v", err)
+	}
+}
+
+func TestLoadPluginPolicy(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "policy.jsonnet")
+	err := os.WriteFile(file, []byte(`{ allow: ['%s.*' % name for name in ['github', 'http']] }`), 0o644)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	policy, err := LoadPluginPolicy(file)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if strings.Join(policy.Allow, ",") != "github.*,http.*" {
+		t.Errorf("unexpected policy: %+v", policy)
+	}
+
+	err = os.WriteFile(file, []byte(`{ deny: ['[a'] }`), 0o644)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	_, err = LoadPluginPolicy(file)
+	if err == nil || !strings.Contains(err.Error(), `invalid policy rule "[a"`) {
+		t.Errorf("expected invalid rule error, got: %v", err)
+	}
+}
@@ -0,0 +1,70 @@
+package jpoet
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/marcbran/jpoet/internal/plugin"
+)
+
+func Retryable(err error) error {
+	return plugin.Retryable(err)
+}
+
+func IsRetryable(err error) bool {
+	return plugin.IsRetryable(err)
+}
+
+func RetryMiddleware(policy RetryPolicy) Middleware {
+	return HookMiddleware(func(next Invoker, funcName string, args []any) (any, error) {
+		backoff := policy.Backoff
+		if backoff == 0 {
+			backoff = plugin.DefaultBackoff
+		}
+		for attempt := 1; ; attempt++ {
+			res, err := next.Invoke(funcName, args)
+			if err == nil || attempt >= policy.Attempts || !IsRetryable(err) {
+				return res, err
+			}
+			unblock := block(next)
+			time.Sleep(backoff)
+			unblock()
+			backoff *= 2
+			if policy.MaxBackoff > 0 {
+				backoff = min(backoff, policy.MaxBackoff)
+			}
+		}
+	})
+}
+
+type TimeoutError struct {
+	FuncName string
+	Timeout  time.Duration
+}
+
+func (e TimeoutError) Error() string {
+	return fmt.Sprintf("invocation of %s timed out after %s", e.FuncName, e.Timeout)
+}
+
+func TimeoutMiddleware(timeouts PluginTimeouts) Middleware {
+	return HookMiddleware(func(next Invoker, funcName string, args []any) (any, error) {
+		timeout := timeouts.For(funcName)
+		if timeout <= 0 {
+			return next.Invoke(funcName, args)
+		}
+		result := make(chan Result, 1)
+		go func() {
+			res, err := next.Invoke(funcName, args)
+			result <- Result{Value: res, Err: err}
+		}()
+		timer := time.NewTimer(timeout)
+		defer timer.Stop()
+		select {
+		case r := <-result:
+			return r.Value, r.Err
+		case <-timer.C:
+			plugin.Abort(next)
+			return nil, TimeoutError{FuncName: funcName, Timeout: timeout}
+		}
+	})
+}
@@ -0,0 +1,128 @@
+package jpoet
+
+import (
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type failingInvoker struct {
+	calls    atomic.Int32
+	failures int32
+	err      error
+}
+
+func (f *failingInvoker) Invoke(funcName string, args []any) (any, error) {
+	if f.calls.Add(1) <= f.failures {
+		return nil, f.err
+	}
+	return "ok", nil
+}
+
+func TestRetryMiddleware(t *testing.T) {
+	next := &failingInvoker{failures: 2, err: Retryable(errors.New("busy"))}
+	invoker := RetryMiddleware(RetryPolicy{Attempts: 3, Backoff: time.Millisecond})(next)
+
+	res, err := invoker.Invoke("fetch", []any{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if res != "ok" {
+		t.Errorf("expected ok, got %v", res)
+	}
+	if next.calls.Load() != 3 {
+		t.Errorf("expected 3 calls, got %d", next.calls.Load())
+	}
+}
+
+func TestRetryMiddleware_Exhausted(t *testing.T) {
+	next := &failingInvoker{failures: 5, err: Retryable(errors.New("busy"))}
+	invoker := RetryMiddleware(RetryPolicy{Attempts: 3, Backoff: time.Millisecond})(next)
+
+	_, err := invoker.Invoke("fetch", []any{})
+	if err == nil || err.Error() != "busy" {
+		t.Errorf("expected busy error, got: %v", err)
+	}
+	if next.calls.Load() != 3 {
+		t.Errorf("expected 3 calls, got %d", next.calls.Load())
+	}
+}
+
+func TestRetryMiddleware_NotRetryable(t *testing.T) {
+	next := &failingInvoker{failures: 5, err: errors.New("invalid argument")}
+	invoker := RetryMiddleware(RetryPolicy{Attempts: 3, Backoff: time.Millisecond})(next)
+
+	_, err := invoker.Invoke("fetch", []any{})
+	if err == nil {
+		t.Fatalf("expected error")
+	}
+	if next.calls.Load() != 1 {
+		t.Errorf("expected 1 call, got %d", next.calls.Load())
+	}
+}
+
+func TestTimeoutMiddleware(t *testing.T) {
+	next := &blockingInvoker{release: make(chan struct{})}
+	defer close(next.release)
+	invoker := TimeoutMiddleware(PluginTimeouts{Functions: map[string]time.Duration{"upper": 10 * time.Millisecond}})(next)
+
+	_, err := invoker.Invoke("upper", []any{"a"})
+	var timeoutErr TimeoutError
+	if !errors.As(err, &timeoutErr) || timeoutErr.FuncName != "upper" {
+		t.Errorf("expected timeout error, got: %v", err)
+	}
+}
+
+func TestTimeoutMiddleware_InTime(t *testing.T) {
+	next := &failingInvoker{}
+	invoker := TimeoutMiddleware(PluginTimeouts{Default: time.Second})(next)
+
+	res, err := invoker.Invoke("fetch", []any{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if res != "ok" {
+		t.Errorf("expected ok, got %v", res)
+	}
+}
+
+type abortableInvoker struct {
+	aborted atomic.Int32
+	stop    chan struct{}
+}
+
+func (a *abortableInvoker) Invoke(funcName string, args []any) (any, error) {
+	if funcName == "hang" {
+		<-a.stop
+		return nil, errors.New("plugin was stopped")
+	}
+	return "ok", nil
+}
+
+func (a *abortableInvoker) Abort() {
+	a.aborted.Add(1)
+	close(a.stop)
+}
+
+func TestTimeoutMiddleware_AbortsPlugin(t *testing.T) {
+	next := &abortableInvoker{stop: make(chan struct{})}
+	f := (&Plugin{name: "test", invoker: next}).WithMiddleware(TimeoutMiddleware(PluginTimeouts{Default: 10 * time.Millisecond})).NativeFunction()
+
+	_, err := f.Func([]any{"hang", []any{}})
+	var timeoutErr TimeoutError
+	if !errors.As(err, &timeoutErr) {
+		t.Fatalf("expected timeout error, got: %v", err)
+	}
+	if next.aborted.Load() != 1 {
+		t.Errorf("expected the plugin to be aborted once, got %d", next.aborted.Load())
+	}
+
+	res, err := f.Func([]any{"fetch", []any{}})
+	if err != nil {
+		t.Fatalf("expected the next call to succeed, got: %v", err)
+	}
+	if res != "ok" {
+		t.Errorf("expected ok, got %v", res)
+	}
+}
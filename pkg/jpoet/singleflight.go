@@ -0,0 +1,75 @@
+package jpoet
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+)
+
+type Singleflight struct {
+	mu      sync.Mutex
+	flights map[cacheKey]*flight
+}
+
+type flight struct {
+	done  chan struct{}
+	value any
+	err   error
+}
+
+func NewSingleflight() *Singleflight {
+	return &Singleflight{
+		flights: make(map[cacheKey]*flight),
+	}
+}
+
+func (s *Singleflight) Middleware() PluginMiddleware {
+	return func(info PluginInfo) Middleware {
+		return HookMiddleware(func(next Invoker, funcName string, args []any) (any, error) {
+			return s.invoke(info, next, funcName, args)
+		})
+	}
+}
+
+func (s *Singleflight) invoke(info PluginInfo, next Invoker, funcName string, args []any) (any, error) {
+	if containsHandle(args) || info.CacheHint(funcName).Never {
+		return next.Invoke(funcName, args)
+	}
+	b, err := json.Marshal(args)
+	if err != nil {
+		return next.Invoke(funcName, args)
+	}
+	k := cacheKey{plugin: info.Name, version: info.Version, funcName: funcName, args: string(b)}
+
+	s.mu.Lock()
+	f, ok := s.flights[k]
+	if ok {
+		s.mu.Unlock()
+		unblock := block(next)
+		<-f.done
+		unblock()
+		if containsHandle(f.value) {
+			return next.Invoke(funcName, args)
+		}
+		return f.value, f.err
+	}
+	f = &flight{done: make(chan struct{})}
+	s.flights[k] = f
+	s.mu.Unlock()
+
+	defer func() {
+		r := recover()
+		if r != nil {
+			f.value, f.err = nil, fmt.Errorf("invoking %s of plugin %s panicked: %v", funcName, info.Name, r)
+		}
+		s.mu.Lock()
+		delete(s.flights, k)
+		s.mu.Unlock()
+		close(f.done)
+		if r != nil {
+			panic(r)
+		}
+	}()
+	f.value, f.err = next.Invoke(funcName, args)
+	return f.value, f.err
+}
@@ -0,0 +1,128 @@
+package jpoet
+
+import (
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type blockingInvoker struct {
+	calls   atomic.Int32
+	release chan struct{}
+}
+
+func (b *blockingInvoker) Invoke(funcName string, args []any) (any, error) {
+	b.calls.Add(1)
+	<-b.release
+	return strings.ToUpper(args[0].(string)), nil
+}
+
+func TestSingleflight_Collapse(t *testing.T) {
+	next := &blockingInvoker{release: make(chan struct{})}
+	invoker := NewSingleflight().Middleware()(PluginInfo{Name: "test"})(next)
+
+	var wg sync.WaitGroup
+	results := make([]any, 5)
+	for i := range results {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i], _ = invoker.Invoke("upper", []any{"a"})
+		}()
+	}
+	time.Sleep(50 * time.Millisecond)
+	close(next.release)
+	wg.Wait()
+
+	if next.calls.Load() != 1 {
+		t.Errorf("expected 1 call, got %d", next.calls.Load())
+	}
+	for _, res := range results {
+		if res != "A" {
+			t.Errorf("unexpected results: %v", results)
+			break
+		}
+	}
+}
+
+type panickingInvoker struct {
+	release chan struct{}
+}
+
+func (p *panickingInvoker) Invoke(funcName string, args []any) (any, error) {
+	<-p.release
+	panic("boom")
+}
+
+func TestSingleflight_Panic(t *testing.T) {
+	next := &panickingInvoker{release: make(chan struct{})}
+	invoker := NewSingleflight().Middleware()(PluginInfo{Name: "test"})(next)
+
+	leader := make(chan any)
+	go func() {
+		defer func() {
+			leader <- recover()
+		}()
+		_, _ = invoker.Invoke("upper", []any{"a"})
+	}()
+	time.Sleep(50 * time.Millisecond)
+	waiter := make(chan error)
+	go func() {
+		_, err := invoker.Invoke("upper", []any{"a"})
+		waiter <- err
+	}()
+	time.Sleep(50 * time.Millisecond)
+	close(next.release)
+
+	if r := <-leader; r != "boom" {
+		t.Errorf("expected the leader to panic, got: %v", r)
+	}
+	err := <-waiter
+	if err == nil || !strings.Contains(err.Error(), "invoking upper of plugin test panicked: boom") {
+		t.Errorf("expected panic error, got: %v", err)
+	}
+}
+
+func TestSingleflight_Never(t *testing.T) {
+	next := &blockingInvoker{release: make(chan struct{})}
+	close(next.release)
+	p := (&Plugin{name: "test", invoker: next}).WithCacheHints(map[string]CacheHint{"upper": {Never: true}})
+	invoker := NewSingleflight().Middleware()(PluginInfo{Name: "test", plugin: p})(next)
+
+	for range 2 {
+		_, err := invoker.Invoke("upper", []any{"a"})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if next.calls.Load() != 2 {
+		t.Errorf("expected 2 calls, got %d", next.calls.Load())
+	}
+}
+
+func TestSingleflight_Batch(t *testing.T) {
+	invoker := &recordingBatchInvoker{}
+	p := (&Plugin{name: "test", invoker: invoker}).WithPluginMiddleware(NewSingleflight().Middleware())
+
+	var out []string
+	err := Eval(
+		SnippetInput("main.jsonnet", `std.native('invokeBatch:test')([
+			{ funcName: 'upper', args: [x] }
+			for x in ['a', 'a', 'b']
+		])`),
+		WithNativeFunction(p.BatchNativeFunction()),
+		Serialize(false),
+		ValueOutput(&out),
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if strings.Join(out, ",") != "A,A,B" {
+		t.Errorf("unexpected results: %v", out)
+	}
+	if len(invoker.batches) != 1 || len(invoker.batches[0]) != 2 {
+		t.Errorf("expected the identical calls to be collapsed in a single batch, got %v", invoker.batches)
+	}
+}
@@ -0,0 +1,115 @@
+package jpoet
+
+import (
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/google/go-jsonnet"
+)
+
+type Tracer struct {
+	start time.Time
+
+	mu     sync.Mutex
+	events []traceEvent
+	tracks []bool
+}
+
+type traceEvent struct {
+	Name     string         `json:"name"`
+	Category string         `json:"cat,omitempty"`
+	Phase    string         `json:"ph"`
+	Time     float64        `json:"ts"`
+	Duration float64        `json:"dur,omitempty"`
+	Process  int            `json:"pid"`
+	Thread   int            `json:"tid"`
+	Args     map[string]any `json:"args,omitempty"`
+}
+
+type traceFile struct {
+	TraceEvents     []traceEvent `json:"traceEvents"`
+	DisplayTimeUnit string       `json:"displayTimeUnit"`
+}
+
+func NewTracer() *Tracer {
+	return &Tracer{start: time.Now()}
+}
+
+func (t *Tracer) span(category string, name string, args map[string]any) func() {
+	t.mu.Lock()
+	track := slices.Index(t.tracks, false)
+	if track < 0 {
+		track = len(t.tracks)
+		t.tracks = append(t.tracks, true)
+	}
+	t.tracks[track] = true
+	t.mu.Unlock()
+
+	start := time.Now()
+	return func() {
+		end := time.Now()
+		t.mu.Lock()
+		defer t.mu.Unlock()
+		t.tracks[track] = false
+		t.events = append(t.events, traceEvent{
+			Name:     name,
+			Category: category,
+			Phase:    "X",
+			Time:     t.micros(start),
+			Duration: float64(end.Sub(start).Nanoseconds()) / 1000,
+			Process:  1,
+			Thread:   track + 1,
+			Args:     args,
+		})
+	}
+}
+
+func (t *Tracer) micros(at time.Time) float64 {
+	return float64(at.Sub(t.start).Nanoseconds()) / 1000
+}
+
+func (t *Tracer) Middleware() PluginMiddleware {
+	return func(info PluginInfo) Middleware {
+		return HookMiddleware(func(next Invoker, funcName string, args []any) (any, error) {
+			end := t.span("plugin", info.Name+"."+funcName, map[string]any{"plugin": info.Name, "function": funcName})
+			result, err := next.Invoke(funcName, args)
+			end()
+			return result, err
+		})
+	}
+}
+
+func (t *Tracer) Save(path string) error {
+	t.mu.Lock()
+	events := make([]traceEvent, 0, len(t.events)+1)
+	events = append(events, traceEvent{Name: "process_name", Phase: "M", Process: 1, Args: map[string]any{"name": "jpoet"}})
+	events = append(events, t.events...)
+	t.mu.Unlock()
+	slices.SortStableFunc(events[1:], func(a, b traceEvent) int {
+		return cmp.Compare(a.Time, b.Time)
+	})
+	b, err := json.Marshal(traceFile{TraceEvents: events, DisplayTimeUnit: "ms"})
+	if err != nil {
+		return fmt.Errorf("failed to encode trace: %w", err)
+	}
+	err = writeFileAtomic(path, b)
+	if err != nil {
+		return fmt.Errorf("failed to write trace: %w", err)
+	}
+	return nil
+}
+
+type tracingImporter struct {
+	tracer   *Tracer
+	importer jsonnet.Importer
+}
+
+func (i tracingImporter) Import(importedFrom, importedPath string) (jsonnet.Contents, string, error) {
+	end := i.tracer.span("import", importedPath, map[string]any{"importedFrom": importedFrom, "importedPath": importedPath})
+	defer end()
+	return i.importer.Import(importedFrom, importedPath)
+}
@@ -0,0 +1,86 @@
+package jpoet
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func readTrace(t *testing.T, tracer *Tracer) []traceEvent {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "trace.json")
+	err := tracer.Save(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var f traceFile
+	err = json.Unmarshal(b, &f)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return f.TraceEvents
+}
+
+func TestEval_Tracer(t *testing.T) {
+	tracer := NewTracer()
+	p := NewPlugin("test", nil).WithHook(func(next Invoker, funcName string, args []any) (any, error) {
+		return strings.ToUpper(args[0].(string)), nil
+	}).WithPluginMiddleware(tracer.Middleware())
+
+	var sb strings.Builder
+	err := Eval(
+		WithTracer(tracer),
+		WithPlugin(p),
+		StringImport("lib.libsonnet", `{ a: 'a' }`),
+		SnippetInput("main.jsonnet", `std.native('invoke:test')('upper', [(import 'lib.libsonnet').a])`),
+		WriterOutput(&sb),
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	spans := make(map[string]traceEvent)
+	for _, e := range readTrace(t, tracer) {
+		spans[e.Category+":"+e.Name] = e
+	}
+	for _, name := range []string{"eval:setup", "eval:evaluate", "eval:output", "import:lib.libsonnet", "plugin:test.upper"} {
+		if _, ok := spans[name]; !ok {
+			t.Errorf("expected span %s, got %v", name, spans)
+		}
+	}
+	invocation := spans["plugin:test.upper"]
+	if invocation.Phase != "X" || invocation.Args["plugin"] != "test" || invocation.Args["function"] != "upper" {
+		t.Errorf("unexpected invocation span: %+v", invocation)
+	}
+	evaluate := spans["eval:evaluate"]
+	if invocation.Time < evaluate.Time || invocation.Time+invocation.Duration > evaluate.Time+evaluate.Duration {
+		t.Errorf("expected invocation %+v during evaluation %+v", invocation, evaluate)
+	}
+}
+
+func TestTracer_Tracks(t *testing.T) {
+	tracer := NewTracer()
+	endA := tracer.span("test", "a", nil)
+	endB := tracer.span("test", "b", nil)
+	endB()
+	endC := tracer.span("test", "c", nil)
+	endC()
+	endA()
+
+	tracks := make(map[string]int)
+	for _, e := range readTrace(t, tracer) {
+		tracks[e.Name] = e.Thread
+	}
+	if tracks["a"] == tracks["b"] {
+		t.Errorf("expected overlapping spans on separate tracks, got %v", tracks)
+	}
+	if tracks["b"] != tracks["c"] {
+		t.Errorf("expected consecutive spans to share a track, got %v", tracks)
+	}
+}
@@ -24,11 +24,11 @@
     children: children,
   },
   plugin: {
-    github(repo, version): {
+    github(repo, version, config={}): {
       github: {
         repo: repo,
         version: version,
       },
-    },
+    } + config,
   },
 }